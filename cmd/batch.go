@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tlsctl/internal/tlsquery"
+)
+
+var batchTargets string
+var batchConcurrency int
+var batchTimeout time.Duration
+var batchStartTLS string
+var batchShowPEM bool
+
+var batchCmd = &cobra.Command{
+	Use:   "batch",
+	Short: "Query many TLS endpoints in parallel",
+	Long: `Reads endpoints (one FQDN[:PORT] per line) from a targets file and queries
+them in parallel. Emits one JSON object per target and line, containing either
+the certificate chain or the error for that target.`,
+	Args: cobra.NoArgs,
+	RunE: runBatch,
+}
+
+func init() {
+	rootCmd.AddCommand(batchCmd)
+	batchCmd.Flags().StringVarP(&batchTargets, "targets", "t", "", "File with one endpoint per line (- for stdin)")
+	batchCmd.Flags().IntVarP(&batchConcurrency, "concurrency", "c", 16, "Number of endpoints queried in parallel")
+	batchCmd.Flags().DurationVar(&batchTimeout, "timeout", 10*time.Second, "Per-target timeout for connect and handshake")
+	batchCmd.Flags().StringVar(&batchStartTLS, "starttls", "", "Upgrade a plaintext connection before the handshake ("+strings.Join(tlsquery.StartTLSProtocols(), ", ")+")")
+	batchCmd.Flags().BoolVar(&batchShowPEM, "show-pem", false, "Include PEM-encoded certificate in output")
+	batchCmd.MarkFlagRequired("targets")
+}
+
+// batchResult is the JSON line emitted for each target.
+type batchResult struct {
+	Target   string              `json:"target"`
+	Endpoint string              `json:"endpoint,omitempty"`
+	Result   *tlsquery.ChainInfo `json:"result,omitempty"`
+	Error    string              `json:"error,omitempty"`
+}
+
+func runBatch(cmd *cobra.Command, args []string) error {
+	if batchConcurrency < 1 {
+		return fmt.Errorf("invalid concurrency: must be at least 1")
+	}
+
+	var in io.Reader = os.Stdin
+	if batchTargets != "-" {
+		f, err := os.Open(batchTargets)
+		if err != nil {
+			return fmt.Errorf("failed to read targets: %w", err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	targets, err := readTargets(in)
+	if err != nil {
+		return fmt.Errorf("failed to read targets: %w", err)
+	}
+
+	opts := tlsquery.QueryOptions{StartTLS: batchStartTLS, Timeout: batchTimeout}
+	query := func(target string) batchResult {
+		result := batchResult{Target: target}
+		endpoint, err := normalizeEndpoint(target, tlsquery.DefaultPort(batchStartTLS))
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		result.Endpoint = endpoint
+
+		chain, err := tlsquery.QueryContext(cmd.Context(), endpoint, opts)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		if !batchShowPEM {
+			chain = stripPEM(chain)
+		}
+		result.Result = chain
+		return result
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	var encodeErr error
+	queryTargets(targets, batchConcurrency, query, func(r batchResult) {
+		if err := encoder.Encode(r); err != nil && encodeErr == nil {
+			encodeErr = err
+		}
+	})
+	return encodeErr
+}
+
+// readTargets returns the endpoints listed in r, skipping blank lines and
+// lines starting with #.
+func readTargets(r io.Reader) ([]string, error) {
+	var targets []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		targets = append(targets, line)
+	}
+	return targets, scanner.Err()
+}
+
+// queryTargets runs query for every target using a pool of concurrency
+// workers. emit is called once per target, never concurrently.
+func queryTargets(targets []string, concurrency int, query func(string) batchResult, emit func(batchResult)) {
+	jobs := make(chan string)
+	results := make(chan batchResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for target := range jobs {
+				results <- query(target)
+			}
+		}()
+	}
+
+	go func() {
+		for _, target := range targets {
+			jobs <- target
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	for r := range results {
+		emit(r)
+	}
+}
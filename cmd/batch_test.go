@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReadTargets(t *testing.T) {
+	input := `# production
+example.com
+  example.org:8443
+
+# staging
+mail.example.com:25
+`
+	got, err := readTargets(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("readTargets() unexpected error: %v", err)
+	}
+
+	want := []string{"example.com", "example.org:8443", "mail.example.com:25"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("readTargets() = %v, want %v", got, want)
+	}
+}
+
+func TestQueryTargets(t *testing.T) {
+	targets := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+	const concurrency = 3
+
+	var running, peak int32
+	query := func(target string) batchResult {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+
+		if target == "c" {
+			return batchResult{Target: target, Error: "unreachable"}
+		}
+		return batchResult{Target: target}
+	}
+
+	var got []string
+	var errors int
+	queryTargets(targets, concurrency, query, func(r batchResult) {
+		got = append(got, r.Target)
+		if r.Error != "" {
+			errors++
+		}
+	})
+
+	sort.Strings(got)
+	if strings.Join(got, "") != strings.Join(targets, "") {
+		t.Errorf("emitted targets = %v, want %v", got, targets)
+	}
+	if errors != 1 {
+		t.Errorf("got %d errors, want 1", errors)
+	}
+	if peak > concurrency {
+		t.Errorf("peak concurrency = %d, want at most %d", peak, concurrency)
+	}
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tlsctl/internal/tlsquery"
+)
+
+var checkFile string
+var checkWarn string
+var checkCrit string
+var checkStartTLS string
+var checkTimeout time.Duration
+
+var checkCmd = &cobra.Command{
+	Use:   "check [FQDN[:PORT]]",
+	Short: "Check certificate expiry with Nagios-style exit codes",
+	Long: `Checks the expiry of every certificate served by an endpoint or stored in a
+PEM file and exits with 0 (OK), 1 (WARNING), 2 (CRITICAL) or 3 (UNKNOWN).
+Prints a one-line summary followed by perfdata. An endpoint that does not
+answer within --timeout is reported as UNKNOWN.`,
+	Args:          cobra.MaximumNArgs(1),
+	RunE:          runCheck,
+	SilenceErrors: true,
+	SilenceUsage:  true,
+}
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+	checkCmd.Flags().StringVarP(&checkFile, "file", "f", "", "Check certificates from a PEM file instead of an endpoint")
+	checkCmd.Flags().StringVar(&checkWarn, "warn", "30d", "WARNING threshold (e.g. 30d, 72h)")
+	checkCmd.Flags().StringVar(&checkCrit, "crit", "7d", "CRITICAL threshold (e.g. 7d, 24h)")
+	checkCmd.Flags().DurationVar(&checkTimeout, "timeout", 10*time.Second, "Timeout for connect and handshake (0 disables)")
+	checkCmd.Flags().StringVar(&checkStartTLS, "starttls", "", "Upgrade a plaintext connection before the handshake ("+strings.Join(tlsquery.StartTLSProtocols(), ", ")+")")
+	checkCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return checkResult(tlsquery.CheckUnknown, err.Error())
+	})
+}
+
+func runCheck(cmd *cobra.Command, args []string) error {
+	warn, err := parseThreshold(checkWarn)
+	if err != nil {
+		return checkResult(tlsquery.CheckUnknown, fmt.Sprintf("invalid --warn: %v", err))
+	}
+	crit, err := parseThreshold(checkCrit)
+	if err != nil {
+		return checkResult(tlsquery.CheckUnknown, fmt.Sprintf("invalid --crit: %v", err))
+	}
+
+	var chain *tlsquery.ChainInfo
+	switch {
+	case checkFile != "" && len(args) > 0:
+		return checkResult(tlsquery.CheckUnknown, "specify either an endpoint or --file, not both")
+	case checkFile != "":
+		chain, err = tlsquery.ParsePEMFile(checkFile)
+	case len(args) == 1:
+		var endpoint string
+		endpoint, err = normalizeEndpoint(args[0], tlsquery.DefaultPort(checkStartTLS))
+		if err == nil {
+			opts := tlsquery.QueryOptions{StartTLS: checkStartTLS, Timeout: checkTimeout}
+			chain, err = tlsquery.QueryContext(cmd.Context(), endpoint, opts)
+		}
+	default:
+		return checkResult(tlsquery.CheckUnknown, "an endpoint or --file is required")
+	}
+	if err != nil {
+		return checkResult(tlsquery.CheckUnknown, err.Error())
+	}
+
+	result, err := tlsquery.CheckExpiry(chain, warn, crit, time.Now())
+	if err != nil {
+		return checkResult(tlsquery.CheckUnknown, err.Error())
+	}
+
+	fmt.Println(result)
+	if result.Status != tlsquery.CheckOK {
+		return &exitCodeError{code: int(result.Status)}
+	}
+	return nil
+}
+
+// checkResult prints a plugin output line without perfdata and returns the
+// matching exit code.
+func checkResult(status tlsquery.CheckStatus, summary string) error {
+	fmt.Println(&tlsquery.ExpiryCheck{Status: status, Summary: summary})
+	return &exitCodeError{code: int(status)}
+}
+
+// parseThreshold parses a duration that may use a "d" suffix for days.
+func parseThreshold(s string) (time.Duration, error) {
+	if n, ok := strings.CutSuffix(s, "d"); ok {
+		days, err := strconv.Atoi(n)
+		if err != nil || days < 0 {
+			return 0, fmt.Errorf("invalid number of days: %q", s)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
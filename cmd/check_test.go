@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tlsctl/internal/tlsquery"
+)
+
+func TestParseThreshold(t *testing.T) {
+	tests := []struct {
+		input     string
+		want      time.Duration
+		wantError bool
+	}{
+		{input: "30d", want: 30 * 24 * time.Hour},
+		{input: "0d", want: 0},
+		{input: "72h", want: 72 * time.Hour},
+		{input: "90m", want: 90 * time.Minute},
+		{input: "xd", wantError: true},
+		{input: "-1d", wantError: true},
+		{input: "soon", wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseThreshold(tt.input)
+			if tt.wantError {
+				if err == nil {
+					t.Errorf("parseThreshold(%q) expected error, got nil", tt.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("parseThreshold(%q) unexpected error: %v", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("parseThreshold(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunCheck_Timeout(t *testing.T) {
+	// The listener accepts connections but never answers the handshake.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	saved := checkTimeout
+	checkTimeout = 100 * time.Millisecond
+	defer func() { checkTimeout = saved }()
+
+	cmd := &cobra.Command{}
+	cmd.SetContext(context.Background())
+	err = runCheck(cmd, []string{listener.Addr().String()})
+	var exitErr *exitCodeError
+	if !errors.As(err, &exitErr) || exitErr.code != int(tlsquery.CheckUnknown) {
+		t.Errorf("expected UNKNOWN exit code, got %v", err)
+	}
+}
@@ -1,13 +1,19 @@
 package cmd
 
 import (
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 
 	"gopkg.in/yaml.v3"
 	"fmt"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/tlsctl/internal/tlsquery"
@@ -15,6 +21,23 @@ import (
 
 var outputFormat string
 var showPEM bool
+var caFile string
+var startTLS string
+var alpn []string
+var serverName string
+var connectTo string
+var proxy string
+var timeout time.Duration
+var resolveAll bool
+var clientCert string
+var clientKey string
+var clientP12 string
+var checkOCSP bool
+var checkCRL bool
+var checkResumption bool
+var crlFiles []string
+var ctLogList string
+var noAIA bool
 
 var clientCmd = &cobra.Command{
 	Use:   "client FQDN[:PORT]",
@@ -28,123 +51,588 @@ func init() {
 	rootCmd.AddCommand(clientCmd)
 	clientCmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Output format (text, json, yaml)")
 	clientCmd.Flags().BoolVar(&showPEM, "show-pem", false, "Include PEM-encoded certificate in output")
+	clientCmd.Flags().StringVar(&caFile, "ca-file", "", "PEM bundle of trusted roots used for verification (default: system roots)")
+	clientCmd.Flags().StringSliceVar(&alpn, "alpn", nil, "Application protocols to offer via ALPN (e.g. h2,http/1.1)")
+	clientCmd.Flags().StringVar(&startTLS, "starttls", "", "Upgrade a plaintext connection before the handshake ("+strings.Join(tlsquery.StartTLSProtocols(), ", ")+")")
+	clientCmd.Flags().StringVar(&serverName, "servername", "", "Server name to send as SNI and verify against (default: endpoint host)")
+	clientCmd.Flags().StringVar(&connectTo, "connect-to", "", "Connect to HOST[:PORT] instead of the endpoint, keeping the endpoint host as SNI")
+	clientCmd.Flags().StringVar(&proxy, "proxy", "", "Connect through a proxy (http://, socks5:// or socks5h:// URL)")
+	clientCmd.Flags().BoolVar(&resolveAll, "resolve-all", false, "Query every A/AAAA address of the host and compare the served certificates")
+	clientCmd.Flags().DurationVar(&timeout, "timeout", 10*time.Second, "Timeout for connect and handshake (0 disables)")
+	clientCmd.Flags().StringVar(&clientCert, "cert", "", "PEM client certificate (chain) to offer when the server requests one")
+	clientCmd.Flags().StringVar(&clientKey, "key", "", "PEM private key for --cert (default: read from the --cert file)")
+	clientCmd.Flags().BoolVar(&checkOCSP, "check-ocsp", false, "Check the revocation status of each certificate with its OCSP responder")
+	clientCmd.Flags().BoolVar(&checkCRL, "check-crl", false, "Check the revocation status of each certificate against its CRL distribution point")
+	clientCmd.Flags().StringSliceVar(&crlFiles, "crl", nil, "Local CRL file (PEM or DER) to check against instead of downloading (implies --check-crl)")
+	clientCmd.Flags().BoolVar(&checkResumption, "check-resumption", false, "Reconnect to check whether the server resumes the session")
+	clientCmd.Flags().BoolVar(&noAIA, "no-aia", false, "Do not fetch missing intermediates from AIA caIssuers URLs")
+	clientCmd.Flags().StringVar(&ctLogList, "ct-logs", "", "CT log list JSON (v3 schema) to verify SCT signatures against")
+	clientCmd.Flags().StringVar(&clientP12, "p12", "", "PKCS#12 client certificate and key (password from $"+p12PasswordEnv+" or prompt)")
 }
 
 func runClient(cmd *cobra.Command, args []string) error {
-	endpoint, err := normalizeEndpoint(args[0])
+	endpoint, err := normalizeEndpoint(args[0], tlsquery.DefaultPort(startTLS))
 	if err != nil {
 		return err
 	}
 
-	certInfo, err := tlsquery.Query(endpoint)
+	opts := tlsquery.QueryOptions{
+		ServerName:      serverName,
+		StartTLS:        startTLS,
+		ALPN:            alpn,
+		Timeout:         timeout,
+		CheckResumption: checkResumption,
+		FetchIssuers:    !noAIA,
+	}
+	if connectTo != "" {
+		endpoint, opts.ServerName, err = connectToEndpoint(endpoint, connectTo, serverName)
+		if err != nil {
+			return err
+		}
+	}
+	if opts.Proxy, err = parseProxy(proxy); err != nil {
+		return err
+	}
+	if caFile != "" {
+		opts.RootCAs, err = tlsquery.LoadCertPool(caFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	if clientCert != "" || clientKey != "" || clientP12 != "" {
+		cert, err := loadClientCertificate(clientCert, clientKey, clientP12)
+		if err != nil {
+			return err
+		}
+		opts.Certificates = []tls.Certificate{*cert}
+	}
+
+	if resolveAll {
+		if connectTo != "" {
+			return fmt.Errorf("--resolve-all cannot be combined with --connect-to")
+		}
+		result, err := tlsquery.QueryAllAddresses(cmd.Context(), endpoint, opts)
+		if err != nil {
+			return err
+		}
+		return outputResolve(result, outputFormat, showPEM)
+	}
+
+	certInfo, err := tlsquery.QueryContext(cmd.Context(), endpoint, opts)
+	var authErr *tlsquery.ClientAuthError
+	if errors.As(err, &authErr) {
+		if outputErr := outputClientAuthError(authErr, outputFormat); outputErr != nil {
+			return outputErr
+		}
+	}
 	if err != nil {
 		return err
 	}
+	httpClient := &http.Client{Timeout: timeout}
+	if checkOCSP {
+		tlsquery.CheckOCSP(cmd.Context(), certInfo, httpClient)
+	}
+	if checkCRL || len(crlFiles) > 0 {
+		if err := tlsquery.CheckCRL(cmd.Context(), certInfo, httpClient, crlFiles); err != nil {
+			return err
+		}
+	}
+	if ctLogList != "" {
+		logs, err := tlsquery.LoadCTLogList(ctLogList)
+		if err != nil {
+			return err
+		}
+		tlsquery.VerifySCTs(certInfo, logs)
+	}
 
 	return outputChain(certInfo, outputFormat, showPEM)
 }
 
-func normalizeEndpoint(endpoint string) (string, error) {
-	parts := strings.Split(endpoint, ":")
-	if len(parts) > 2 {
-		return "", fmt.Errorf("invalid endpoint format: expected FQDN[:PORT], got %q", endpoint)
+// parseProxy parses a --proxy URL. An empty value means no proxy.
+func parseProxy(value string) (*url.URL, error) {
+	if value == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy: %q (expected SCHEME://HOST[:PORT])", value)
+	}
+	return u, nil
+}
+
+// connectToEndpoint returns the address to dial for a --connect-to override
+// and the server name to use. The override inherits the endpoint port when it
+// has none, and the endpoint host is kept as server name unless one is given.
+func connectToEndpoint(endpoint, connectTo, serverName string) (string, string, error) {
+	host, port, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return "", "", err
+	}
+
+	addr, err := normalizeEndpoint(connectTo, port)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid --connect-to: %w", err)
+	}
+
+	if serverName == "" {
+		serverName = host
+	}
+	return addr, serverName, nil
+}
+
+// schemePorts maps URL schemes of implicit-TLS protocols to their default
+// port. Other schemes use the default port of the command.
+var schemePorts = map[string]string{
+	"https": "443",
+	"wss":   "443",
+	"smtps": "465",
+	"ldaps": "636",
+	"ftps":  "990",
+	"imaps": "993",
+	"pop3s": "995",
+}
+
+// normalizeEndpoint turns FQDN[:PORT], IPv6 literals (bare or bracketed
+// with a port) and URLs into a HOST:PORT dial address.
+func normalizeEndpoint(endpoint, defaultPort string) (string, error) {
+	if strings.Contains(endpoint, "://") {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return "", fmt.Errorf("invalid endpoint URL: %w", err)
+		}
+		if port, ok := schemePorts[u.Scheme]; ok {
+			defaultPort = port
+		}
+		endpoint = u.Host
+	} else if i := strings.IndexByte(endpoint, '/'); i >= 0 {
+		endpoint = endpoint[:i]
+	}
+
+	var host, port string
+	switch {
+	case strings.HasPrefix(endpoint, "["):
+		end := strings.IndexByte(endpoint, ']')
+		if end < 0 {
+			return "", fmt.Errorf("invalid endpoint format: missing ']' in %q", endpoint)
+		}
+		host = endpoint[1:end]
+		rest := endpoint[end+1:]
+		if rest != "" && !strings.HasPrefix(rest, ":") {
+			return "", fmt.Errorf("invalid endpoint format: unexpected %q after IPv6 address", rest)
+		}
+		port = strings.TrimPrefix(rest, ":")
+		if host != "" && net.ParseIP(host) == nil {
+			return "", fmt.Errorf("invalid endpoint format: %q is not an IPv6 address", host)
+		}
+	case strings.Count(endpoint, ":") > 1:
+		if net.ParseIP(endpoint) == nil {
+			return "", fmt.Errorf("invalid endpoint format: expected FQDN[:PORT], [IPv6]:PORT or URL, got %q", endpoint)
+		}
+		host = endpoint
+	default:
+		host, port, _ = strings.Cut(endpoint, ":")
 	}
 
-	host := parts[0]
 	if host == "" {
 		return "", fmt.Errorf("invalid hostname: hostname cannot be empty")
 	}
 
-	port := "443"
-	if len(parts) == 2 && parts[1] != "" {
-		port = parts[1]
+	if port == "" {
+		port = defaultPort
+	} else {
 		portNum, err := strconv.Atoi(port)
 		if err != nil || portNum < 0 || portNum > 65535 {
 			return "", fmt.Errorf("invalid port: port must be a number in the range 0-65535")
 		}
 	}
 
-	return host + ":" + port, nil
+	return net.JoinHostPort(host, port), nil
 }
 
 func outputChain(chain *tlsquery.ChainInfo, format string, showPEM bool) error {
 	outputChain := chain
 	if !showPEM {
-		outputChain = &tlsquery.ChainInfo{
-			Certificates: make([]tlsquery.CertInfo, len(chain.Certificates)),
-		}
-		for i, cert := range chain.Certificates {
-			outputChain.Certificates[i] = cert
-			outputChain.Certificates[i].PEM = ""
-		}
+		outputChain = stripPEM(chain)
 	}
 
 	switch format {
-	case "json":
-		encoder := json.NewEncoder(os.Stdout)
-		encoder.SetIndent("", "  ")
-		return encoder.Encode(outputChain)
-	case "yaml":
-		encoder := yaml.NewEncoder(os.Stdout)
-		encoder.SetIndent(2)
-		return encoder.Encode(outputChain)
+	case "json", "yaml":
+		return encodeOutput(outputChain, format)
 	case "text":
 		for i, cert := range outputChain.Certificates {
 			if i > 0 {
 				fmt.Println()
 			}
 			fmt.Printf("[%s]\n", strings.ToUpper(cert.Type))
-			fmt.Printf("Version:               %d\n", cert.Version)
-			fmt.Printf("Serial Number:         %s\n", cert.SerialNumber)
-			fmt.Printf("Signature Algorithm:   %s\n", cert.SignatureAlgorithm)
-			fmt.Printf("Issuer:                %s\n", cert.Issuer)
-			fmt.Printf("Subject:               %s\n", cert.Subject)
-			fmt.Printf("Not Before:            %s\n", cert.NotBefore)
-			fmt.Printf("Not After:             %s\n", cert.NotAfter)
-			fmt.Printf("Public Key Algorithm:  %s\n", cert.PublicKeyAlgorithm)
-			if len(cert.KeyUsage) > 0 {
-				fmt.Printf("Key Usage:             %s\n", strings.Join(cert.KeyUsage, ", "))
-			}
-			if len(cert.ExtKeyUsage) > 0 {
-				fmt.Printf("Extended Key Usage:    %s\n", strings.Join(cert.ExtKeyUsage, ", "))
-			}
-			if cert.BasicConstraints != nil {
-				if cert.BasicConstraints.IsCA {
-					fmt.Printf("Basic Constraints:     CA:TRUE, pathlen:%d\n", cert.BasicConstraints.MaxPathLen)
-				} else {
-					fmt.Printf("Basic Constraints:     CA:FALSE\n")
-				}
-			}
-			if cert.SubjectKeyID != "" {
-				fmt.Printf("Subject Key ID:        %s\n", cert.SubjectKeyID)
-			}
-			if cert.AuthorityKeyID != "" {
-				fmt.Printf("Authority Key ID:      %s\n", cert.AuthorityKeyID)
-			}
-			if len(cert.SubjectAltNames) > 0 {
-				fmt.Printf("Subject Alt Names:     %s\n", strings.Join(cert.SubjectAltNames, ", "))
-			}
-			if len(cert.EmailAddresses) > 0 {
-				fmt.Printf("Email Addresses:       %s\n", strings.Join(cert.EmailAddresses, ", "))
-			}
-			if len(cert.IPAddresses) > 0 {
-				fmt.Printf("IP Addresses:          %s\n", strings.Join(cert.IPAddresses, ", "))
-			}
-			if len(cert.OCSPServers) > 0 {
-				fmt.Printf("OCSP Servers:          %s\n", strings.Join(cert.OCSPServers, ", "))
-			}
-			if len(cert.IssuingCertURL) > 0 {
-				fmt.Printf("CA Issuers:            %s\n", strings.Join(cert.IssuingCertURL, ", "))
-			}
-			if len(cert.CRLDistPoints) > 0 {
-				fmt.Printf("CRL Distribution:      %s\n", strings.Join(cert.CRLDistPoints, ", "))
-			}
-			if cert.PEM != "" {
-				fmt.Printf("PEM:\n%s", cert.PEM)
-			}
+			printCertInfo(&cert)
+		}
+		if outputChain.Connection != nil {
+			printConnection(outputChain.Connection)
+		}
+		if outputChain.StapledOCSP != nil {
+			printStapledOCSP(outputChain.StapledOCSP)
+		}
+		if outputChain.ClientCertRequest != nil {
+			printClientCertRequest(outputChain.ClientCertRequest)
+		}
+		if outputChain.Analysis != nil {
+			printChainAnalysis(outputChain)
+		}
+		if len(outputChain.SkippedBlocks) > 0 {
+			printSkippedBlocks(outputChain.SkippedBlocks)
+		}
+		if outputChain.Verification != nil {
+			printVerification("VERIFICATION", outputChain.Verification)
+		}
+		if outputChain.CompletedVerification != nil {
+			printVerification("VERIFICATION WITH FETCHED ISSUERS", outputChain.CompletedVerification)
 		}
 		return nil
 	default:
 		return fmt.Errorf("invalid output format: %q (valid: text, json, yaml)", format)
 	}
 }
+
+// printCertInfo prints the fields of a certificate, below its section
+// header.
+func printCertInfo(cert *tlsquery.CertInfo) {
+	if cert.Source != "" {
+		fmt.Printf("Source:                %s\n", cert.Source)
+	}
+	fmt.Printf("Version:               %d\n", cert.Version)
+	fmt.Printf("Serial Number:         %s\n", cert.SerialNumber)
+	fmt.Printf("Signature Algorithm:   %s\n", cert.SignatureAlgorithm)
+	fmt.Printf("Issuer:                %s\n", cert.Issuer)
+	fmt.Printf("Subject:               %s\n", cert.Subject)
+	fmt.Printf("Not Before:            %s\n", cert.NotBefore)
+	fmt.Printf("Not After:             %s\n", cert.NotAfter)
+	printPublicKey(cert.PublicKeyAlgorithm, cert.PublicKey)
+	if len(cert.KeyUsage) > 0 {
+		fmt.Printf("Key Usage:             %s\n", strings.Join(cert.KeyUsage, ", "))
+	}
+	if len(cert.ExtKeyUsage) > 0 {
+		fmt.Printf("Extended Key Usage:    %s\n", strings.Join(cert.ExtKeyUsage, ", "))
+	}
+	if cert.BasicConstraints != nil {
+		if cert.BasicConstraints.IsCA {
+			fmt.Printf("Basic Constraints:     CA:TRUE, pathlen:%d\n", cert.BasicConstraints.MaxPathLen)
+		} else {
+			fmt.Printf("Basic Constraints:     CA:FALSE\n")
+		}
+	}
+	if cert.SubjectKeyID != "" {
+		fmt.Printf("Subject Key ID:        %s\n", cert.SubjectKeyID)
+	}
+	if cert.AuthorityKeyID != "" {
+		fmt.Printf("Authority Key ID:      %s\n", cert.AuthorityKeyID)
+	}
+	if len(cert.SubjectAltNames) > 0 {
+		fmt.Printf("Subject Alt Names:     %s\n", strings.Join(cert.SubjectAltNames, ", "))
+	}
+	if len(cert.EmailAddresses) > 0 {
+		fmt.Printf("Email Addresses:       %s\n", strings.Join(cert.EmailAddresses, ", "))
+	}
+	if len(cert.IPAddresses) > 0 {
+		fmt.Printf("IP Addresses:          %s\n", strings.Join(cert.IPAddresses, ", "))
+	}
+	if len(cert.OCSPServers) > 0 {
+		fmt.Printf("OCSP Servers:          %s\n", strings.Join(cert.OCSPServers, ", "))
+	}
+	if len(cert.IssuingCertURL) > 0 {
+		fmt.Printf("CA Issuers:            %s\n", strings.Join(cert.IssuingCertURL, ", "))
+	}
+	if len(cert.CRLDistPoints) > 0 {
+		fmt.Printf("CRL Distribution:      %s\n", strings.Join(cert.CRLDistPoints, ", "))
+	}
+	for i, sct := range cert.SCTs {
+		printSCT(i, &sct)
+	}
+	if cert.AIAError != "" {
+		fmt.Printf("AIA Error:             %s\n", cert.AIAError)
+	}
+	if cert.OCSP != nil {
+		printOCSP(cert.OCSP)
+	}
+	if cert.CRL != nil {
+		printCRL(cert.CRL)
+	}
+	if cert.PEM != "" {
+		fmt.Printf("PEM:\n%s", cert.PEM)
+	}
+}
+
+func outputResolve(result *tlsquery.ResolveResult, format string, showPEM bool) error {
+	if !showPEM {
+		stripped := *result
+		stripped.Addresses = make([]tlsquery.AddressResult, len(result.Addresses))
+		for i, addr := range result.Addresses {
+			if addr.Chain != nil {
+				addr.Chain = stripPEM(addr.Chain)
+			}
+			stripped.Addresses[i] = addr
+		}
+		result = &stripped
+	}
+
+	if format != "text" {
+		return encodeOutput(result, format)
+	}
+
+	fmt.Printf("Hostname:              %s\n", result.Hostname)
+	fmt.Printf("Port:                  %s\n", result.Port)
+	if result.Consistent {
+		fmt.Printf("Consistent:            yes (%d addresses serve the same certificate)\n", len(result.Addresses))
+	} else {
+		fmt.Printf("Consistent:            NO\n")
+	}
+	for _, addr := range result.Addresses {
+		fmt.Println()
+		fmt.Printf("[%s]\n", addr.Address)
+		if addr.Error != "" {
+			fmt.Printf("Error:                 %s\n", addr.Error)
+			continue
+		}
+		leaf := addr.Chain.Certificates[0]
+		fmt.Printf("Subject:               %s\n", leaf.Subject)
+		fmt.Printf("Not After:             %s\n", leaf.NotAfter)
+		fmt.Printf("SHA256 Fingerprint:    %s\n", addr.Fingerprint)
+		if v := addr.Chain.Verification; v != nil && !v.Verified {
+			fmt.Printf("Verification:          FAILED (%s)\n", v.Reason)
+		}
+	}
+	return nil
+}
+
+// clientAuthFailure is the structured output for a handshake rejected by
+// a server that requested a client certificate.
+type clientAuthFailure struct {
+	Error             string                      `json:"error"`
+	ClientCertRequest *tlsquery.ClientCertRequest `json:"client_certificate_request"`
+}
+
+// outputClientAuthError shows what the server asked for before the
+// handshake failed, so the error can be diagnosed.
+func outputClientAuthError(err *tlsquery.ClientAuthError, format string) error {
+	if format != "text" {
+		return encodeOutput(clientAuthFailure{Error: err.Error(), ClientCertRequest: err.Request}, format)
+	}
+	fmt.Printf("Handshake failed after the server requested a client certificate.\n")
+	printClientCertRequest(err.Request)
+	return nil
+}
+
+// stripPEM returns a copy of chain without the PEM-encoded certificates.
+func stripPEM(chain *tlsquery.ChainInfo) *tlsquery.ChainInfo {
+	stripped := *chain
+	stripped.Certificates = make([]tlsquery.CertInfo, len(chain.Certificates))
+	for i, cert := range chain.Certificates {
+		stripped.Certificates[i] = cert
+		stripped.Certificates[i].PEM = ""
+	}
+	return &stripped
+}
+
+// encodeOutput writes v to stdout in the given structured format (json or yaml).
+func encodeOutput(v any, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	case "yaml":
+		encoder := yaml.NewEncoder(os.Stdout)
+		encoder.SetIndent(2)
+		return encoder.Encode(v)
+	default:
+		return fmt.Errorf("invalid output format: %q (valid: text, json, yaml)", format)
+	}
+}
+
+func printConnection(c *tlsquery.ConnectionInfo) {
+	fmt.Println()
+	fmt.Println("[CONNECTION]")
+	fmt.Printf("TLS Version:           %s\n", c.Version)
+	fmt.Printf("Cipher Suite:          %s\n", c.CipherSuite)
+	if c.KeyExchange != "" {
+		fmt.Printf("Key Exchange:          %s\n", c.KeyExchange)
+	}
+	if c.ALPN != "" {
+		fmt.Printf("ALPN Protocol:         %s\n", c.ALPN)
+	}
+	if c.ServerName != "" {
+		fmt.Printf("Server Name (SNI):     %s\n", c.ServerName)
+	}
+	fmt.Printf("OCSP Stapled:          %t\n", c.OCSPStapled)
+	fmt.Printf("SCTs Delivered:        %t\n", c.SCTsDelivered)
+	if c.ResumptionSupported != nil {
+		fmt.Printf("Session Resumption:    %t\n", *c.ResumptionSupported)
+	}
+}
+
+func printSCT(index int, sct *tlsquery.SCT) {
+	label := fmt.Sprintf("SCT %d:", index+1)
+	if sct.Error != "" && sct.Status == "" {
+		fmt.Printf("%-23s%s, %s\n", label, sct.Source, sct.Error)
+		return
+	}
+	log := sct.LogID
+	if sct.LogName != "" {
+		log = sct.LogName
+	}
+	line := fmt.Sprintf("%s, %s, %s, %s", sct.Source, log, sct.Timestamp, sct.SignatureAlgorithm)
+	switch sct.Status {
+	case "":
+	case tlsquery.SCTInvalid:
+		line += fmt.Sprintf(" (invalid: %s)", sct.Error)
+	default:
+		line += fmt.Sprintf(" (%s)", sct.Status)
+	}
+	fmt.Printf("%-23s%s\n", label, line)
+}
+
+func printOCSP(r *tlsquery.OCSPResult) {
+	fmt.Printf("OCSP Responder:        %s\n", r.Responder)
+	if r.Error != "" {
+		fmt.Printf("OCSP Error:            %s\n", r.Error)
+		return
+	}
+	if r.Status == "revoked" {
+		fmt.Printf("OCSP Status:           revoked at %s (%s)\n", r.RevokedAt, r.RevocationReason)
+	} else {
+		fmt.Printf("OCSP Status:           %s\n", r.Status)
+	}
+	fmt.Printf("OCSP This Update:      %s\n", r.ThisUpdate)
+	if r.NextUpdate != "" {
+		fmt.Printf("OCSP Next Update:      %s\n", r.NextUpdate)
+	}
+}
+
+func printCRL(r *tlsquery.CRLResult) {
+	fmt.Printf("CRL Source:            %s\n", r.Source)
+	if r.Error != "" {
+		fmt.Printf("CRL Error:             %s\n", r.Error)
+		return
+	}
+	if r.Status == "revoked" {
+		fmt.Printf("CRL Status:            revoked at %s (%s)\n", r.RevokedAt, r.RevocationReason)
+	} else {
+		fmt.Printf("CRL Status:            %s\n", r.Status)
+	}
+	fmt.Printf("CRL This Update:       %s\n", r.ThisUpdate)
+	if r.Stale {
+		fmt.Printf("CRL Next Update:       %s (STALE)\n", r.NextUpdate)
+	} else if r.NextUpdate != "" {
+		fmt.Printf("CRL Next Update:       %s\n", r.NextUpdate)
+	}
+}
+
+func printStapledOCSP(s *tlsquery.StapledOCSP) {
+	fmt.Println()
+	fmt.Println("[STAPLED OCSP]")
+	fmt.Printf("Stapled:               %t\n", s.Stapled)
+	if s.Status != "" {
+		fmt.Printf("Responder ID:          %s\n", s.ResponderID)
+		if s.Status == "revoked" {
+			fmt.Printf("Status:                revoked at %s (%s)\n", s.RevokedAt, s.RevocationReason)
+		} else {
+			fmt.Printf("Status:                %s\n", s.Status)
+		}
+		fmt.Printf("Produced At:           %s\n", s.ProducedAt)
+		fmt.Printf("This Update:           %s\n", s.ThisUpdate)
+		if s.NextUpdate != "" {
+			fmt.Printf("Next Update:           %s\n", s.NextUpdate)
+		}
+		fmt.Printf("Signature Valid:       %t\n", s.SignatureValid)
+	}
+	for _, issue := range s.Issues {
+		fmt.Printf("Warning:               %s\n", issue)
+	}
+}
+
+func printClientCertRequest(r *tlsquery.ClientCertRequest) {
+	fmt.Println()
+	fmt.Println("[CLIENT CERTIFICATE REQUEST]")
+	fmt.Printf("TLS Version:           %s\n", r.Version)
+	fmt.Printf("Certificate Sent:      %t\n", r.CertificateSent)
+	if len(r.AcceptableCAs) == 0 {
+		fmt.Printf("Acceptable CAs:        any\n")
+	}
+	for i, ca := range r.AcceptableCAs {
+		fmt.Printf("%-23s%s\n", fmt.Sprintf("Acceptable CA %d:", i+1), ca)
+	}
+	if len(r.SignatureAlgorithms) > 0 {
+		fmt.Printf("Signature Algorithms:  %s\n", strings.Join(r.SignatureAlgorithms, ", "))
+	}
+}
+
+// printPublicKey prints the public key lines shared by certificates, CSRs
+// and private keys.
+func printPublicKey(algorithm string, pk *tlsquery.PublicKeyInfo) {
+	fmt.Printf("Public Key Algorithm:  %s\n", algorithm)
+	if pk == nil {
+		return
+	}
+	if pk.Bits > 0 {
+		fmt.Printf("Public Key Size:       %d bits\n", pk.Bits)
+	}
+	if pk.Curve != "" {
+		fmt.Printf("Public Key Curve:      %s\n", pk.Curve)
+	}
+	if pk.Exponent != 0 {
+		fmt.Printf("RSA Exponent:          %d\n", pk.Exponent)
+	}
+	fmt.Printf("SPKI SHA256:           %s\n", pk.SPKISHA256)
+	fmt.Printf("SPKI SHA256 (base64):  %s\n", pk.SPKISHA256Base64)
+}
+
+func printChainAnalysis(chain *tlsquery.ChainInfo) {
+	a := chain.Analysis
+	fmt.Println()
+	fmt.Println("[CHAIN ANALYSIS]")
+	for _, cert := range chain.Certificates {
+		if cert.Source == tlsquery.SourceFetched {
+			fmt.Printf("Scope:                 %s certificates only, fetched issuers not included\n", chain.Certificates[0].Source)
+			break
+		}
+	}
+	fmt.Printf("Ordered:               %t\n", a.Ordered)
+	var path []string
+	for _, index := range a.Path {
+		path = append(path, chain.Certificates[index].Subject)
+	}
+	fmt.Printf("Path:                  %s\n", strings.Join(path, " -> "))
+	for _, issue := range a.Issues {
+		fmt.Printf("Issue:                 %s: %s\n", issue.Type, issue.Message)
+	}
+}
+
+func printSkippedBlocks(blocks []tlsquery.SkippedBlock) {
+	fmt.Println()
+	fmt.Println("[SKIPPED BLOCKS]")
+	for _, b := range blocks {
+		fmt.Printf("%-23s%s\n", fmt.Sprintf("Block %d:", b.Block), b.Type)
+	}
+}
+
+func printVerification(title string, v *tlsquery.Verification) {
+	fmt.Println()
+	fmt.Printf("[%s]\n", title)
+	if v.Verified {
+		fmt.Printf("Status:                OK\n")
+	} else {
+		fmt.Printf("Status:                FAILED (%s)\n", v.Reason)
+	}
+	if v.IncompleteChain {
+		fmt.Printf("Incomplete Chain:      server did not send all intermediates\n")
+	}
+	if v.HostnameMatch != nil {
+		fmt.Printf("Hostname:              %s (match: %t)\n", v.Hostname, *v.HostnameMatch)
+	}
+	for i, chain := range v.Chains {
+		fmt.Printf("Verified Chain %d:      %s\n", i+1, strings.Join(chain, " -> "))
+	}
+	if v.Error != "" {
+		fmt.Printf("Error:                 %s\n", v.Error)
+	}
+}
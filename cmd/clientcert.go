@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/tlsctl/internal/pkcs12"
+)
+
+// p12PasswordEnv holds the PKCS#12 password for non-interactive use.
+const p12PasswordEnv = "TLSCTL_P12_PASSWORD"
+
+// loadClientCertificate loads a client certificate and key from PEM files
+// or a PKCS#12 bundle. keyFile defaults to certFile, so a single PEM file
+// holding both can be used.
+func loadClientCertificate(certFile, keyFile, p12File string) (*tls.Certificate, error) {
+	if p12File != "" {
+		if certFile != "" || keyFile != "" {
+			return nil, fmt.Errorf("--p12 cannot be combined with --cert or --key")
+		}
+		data, err := os.ReadFile(p12File)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file: %w", err)
+		}
+		password, err := readPassword(p12PasswordEnv, "Enter password for "+p12File+": ")
+		if err != nil {
+			return nil, err
+		}
+		store, err := pkcs12.Decode(data, password)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", p12File, err)
+		}
+		return certificateFromStore(store)
+	}
+
+	if certFile == "" {
+		return nil, fmt.Errorf("--key requires --cert")
+	}
+	if keyFile == "" {
+		keyFile = certFile
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load client certificate: %w", err)
+	}
+	return &cert, nil
+}
+
+// certificateFromStore builds a TLS certificate from the first private key
+// in store. The leaf is the certificate with the same local key ID or public
+// key; all other certificates are sent as the chain.
+func certificateFromStore(store *pkcs12.Store) (*tls.Certificate, error) {
+	var key *pkcs12.Bag
+	for i := range store.Bags {
+		if store.Bags[i].PrivateKey != nil {
+			key = &store.Bags[i]
+			break
+		}
+	}
+	if key == nil {
+		return nil, errors.New("no private key found in PKCS#12 file")
+	}
+	signer, ok := key.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key.PrivateKey)
+	}
+	public, ok := signer.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok {
+		return nil, fmt.Errorf("unsupported public key type %T", signer.Public())
+	}
+
+	cert := &tls.Certificate{PrivateKey: key.PrivateKey}
+	var chain [][]byte
+	for _, bag := range store.Bags {
+		if bag.Certificate == nil {
+			continue
+		}
+		isLeaf := len(key.LocalKeyID) > 0 && bytes.Equal(bag.LocalKeyID, key.LocalKeyID)
+		if cert.Leaf == nil && (isLeaf || public.Equal(bag.Certificate.PublicKey)) {
+			cert.Leaf = bag.Certificate
+			continue
+		}
+		chain = append(chain, bag.Certificate.Raw)
+	}
+	if cert.Leaf == nil {
+		return nil, errors.New("no certificate matching the private key found in PKCS#12 file")
+	}
+	cert.Certificate = append([][]byte{cert.Leaf.Raw}, chain...)
+	return cert, nil
+}
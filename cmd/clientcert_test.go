@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/tlsctl/internal/pkcs12"
+)
+
+func newCertificate(t *testing.T, cn string, isCA bool) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	return cert, key
+}
+
+func TestCertificateFromStore(t *testing.T) {
+	ca, _ := newCertificate(t, "CA", true)
+	leaf, key := newCertificate(t, "client", false)
+
+	tests := []struct {
+		name string
+		bags []pkcs12.Bag
+	}{
+		{
+			name: "matched by local key ID",
+			bags: []pkcs12.Bag{
+				{Certificate: ca},
+				{Certificate: leaf, LocalKeyID: []byte{1}},
+				{PrivateKey: key, LocalKeyID: []byte{1}},
+			},
+		},
+		{
+			name: "matched by public key",
+			bags: []pkcs12.Bag{
+				{PrivateKey: key},
+				{Certificate: ca},
+				{Certificate: leaf},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, err := certificateFromStore(&pkcs12.Store{Bags: tt.bags})
+			if err != nil {
+				t.Fatalf("certificateFromStore failed: %v", err)
+			}
+			if cert.Leaf != leaf {
+				t.Errorf("expected leaf %q, got %q", leaf.Subject, cert.Leaf.Subject)
+			}
+			if len(cert.Certificate) != 2 || string(cert.Certificate[1]) != string(ca.Raw) {
+				t.Errorf("expected leaf followed by CA certificate, got %d certificates", len(cert.Certificate))
+			}
+		})
+	}
+}
+
+func TestCertificateFromStore_Errors(t *testing.T) {
+	leaf, _ := newCertificate(t, "client", false)
+	_, otherKey := newCertificate(t, "other", false)
+
+	tests := map[string][]pkcs12.Bag{
+		"no private key":          {{Certificate: leaf}},
+		"no matching certificate": {{Certificate: leaf}, {PrivateKey: otherKey}},
+	}
+	for name, bags := range tests {
+		if _, err := certificateFromStore(&pkcs12.Store{Bags: bags}); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
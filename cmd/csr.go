@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tlsctl/internal/tlsquery"
+)
+
+var csrOutputFormat string
+var csrShowPEM bool
+
+var csrCmd = &cobra.Command{
+	Use:   "csr FILE",
+	Short: "Parse and display certificate signing requests",
+	Long: `Reads PKCS#10 certificate signing requests (PEM or DER), verifies their
+self-signature and displays the subject, public key, requested extensions and
+attributes.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runCSR,
+}
+
+func init() {
+	rootCmd.AddCommand(csrCmd)
+	csrCmd.Flags().StringVarP(&csrOutputFormat, "output", "o", "text", "Output format (text, json, yaml)")
+	csrCmd.Flags().BoolVar(&csrShowPEM, "show-pem", false, "Include PEM-encoded request in output")
+}
+
+func runCSR(cmd *cobra.Command, args []string) error {
+	csrs, err := tlsquery.ParseCSRFile(args[0])
+	if err != nil {
+		return err
+	}
+	if !csrShowPEM {
+		for i := range csrs {
+			csrs[i].PEM = ""
+		}
+	}
+	return outputCSRs(csrs, csrOutputFormat)
+}
+
+// csrList is the structured output of the csr command.
+type csrList struct {
+	Requests []tlsquery.CSRInfo `json:"requests"`
+}
+
+func outputCSRs(csrs []tlsquery.CSRInfo, format string) error {
+	if format != "text" {
+		return encodeOutput(csrList{Requests: csrs}, format)
+	}
+
+	for i, csr := range csrs {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Println("[CERTIFICATE REQUEST]")
+		fmt.Printf("Version:               %d\n", csr.Version)
+		fmt.Printf("Signature Algorithm:   %s\n", csr.SignatureAlgorithm)
+		if csr.SignatureValid {
+			fmt.Printf("Signature:             OK\n")
+		} else {
+			fmt.Printf("Signature:             FAILED (%s)\n", csr.SignatureError)
+		}
+		fmt.Printf("Subject:               %s\n", csr.Subject)
+		printPublicKey(csr.PublicKeyAlgorithm, csr.PublicKey)
+		if len(csr.KeyUsage) > 0 {
+			fmt.Printf("Key Usage:             %s\n", strings.Join(csr.KeyUsage, ", "))
+		}
+		if len(csr.ExtKeyUsage) > 0 {
+			fmt.Printf("Extended Key Usage:    %s\n", strings.Join(csr.ExtKeyUsage, ", "))
+		}
+		if csr.BasicConstraints != nil {
+			if csr.BasicConstraints.IsCA {
+				fmt.Printf("Basic Constraints:     CA:TRUE, pathlen:%d\n", csr.BasicConstraints.MaxPathLen)
+			} else {
+				fmt.Printf("Basic Constraints:     CA:FALSE\n")
+			}
+		}
+		if csr.SubjectKeyID != "" {
+			fmt.Printf("Subject Key ID:        %s\n", csr.SubjectKeyID)
+		}
+		if len(csr.SubjectAltNames) > 0 {
+			fmt.Printf("Subject Alt Names:     %s\n", strings.Join(csr.SubjectAltNames, ", "))
+		}
+		if len(csr.EmailAddresses) > 0 {
+			fmt.Printf("Email Addresses:       %s\n", strings.Join(csr.EmailAddresses, ", "))
+		}
+		if len(csr.IPAddresses) > 0 {
+			fmt.Printf("IP Addresses:          %s\n", strings.Join(csr.IPAddresses, ", "))
+		}
+		if len(csr.URIs) > 0 {
+			fmt.Printf("URIs:                  %s\n", strings.Join(csr.URIs, ", "))
+		}
+		for i, ext := range csr.Extensions {
+			name := ext.OID
+			if ext.Name != "" {
+				name = fmt.Sprintf("%s (%s)", ext.Name, ext.OID)
+			}
+			if ext.Critical {
+				name += ", critical"
+			}
+			fmt.Printf("%-23s%s\n", fmt.Sprintf("Extension %d:", i+1), name)
+		}
+		for i, attr := range csr.Attributes {
+			name := attr.Name
+			if name == "" {
+				name = attr.OID
+			}
+			fmt.Printf("%-23s%s: %s\n", fmt.Sprintf("Attribute %d:", i+1), name, strings.Join(attr.Values, ", "))
+		}
+		fmt.Printf("SHA256 Fingerprint:    %s\n", csr.Fingerprint.SHA256)
+		if csr.PEM != "" {
+			fmt.Printf("PEM:\n%s", csr.PEM)
+		}
+	}
+	return nil
+}
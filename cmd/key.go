@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/tlsctl/internal/tlsquery"
+)
+
+// keyPasswordEnv holds the password of encrypted private keys for
+// non-interactive use.
+const keyPasswordEnv = "TLSCTL_KEY_PASSWORD"
+
+var keyOutputFormat string
+
+var keyCmd = &cobra.Command{
+	Use:   "key FILE",
+	Short: "Display the algorithm, size and fingerprint of a private key",
+	Long: `Reads a PKCS#1, PKCS#8 (optionally encrypted), SEC1 EC or Ed25519 private key
+(PEM or DER) and displays its algorithm, size or curve, and the SHA-256
+fingerprint of its public key, which equals that of certificates issued for it.
+
+The password of an encrypted key is taken from the ` + keyPasswordEnv + `
+environment variable, a terminal prompt, or the first line of stdin.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runKey,
+}
+
+func init() {
+	rootCmd.AddCommand(keyCmd)
+	keyCmd.Flags().StringVarP(&keyOutputFormat, "output", "o", "text", "Output format (text, json, yaml)")
+}
+
+func runKey(cmd *cobra.Command, args []string) error {
+	key, err := loadPrivateKey(args[0])
+	if err != nil {
+		return err
+	}
+	return outputKey(key, keyOutputFormat)
+}
+
+// loadPrivateKey parses the private key in path, asking for the password
+// if it is encrypted.
+func loadPrivateKey(path string) (*tlsquery.KeyInfo, error) {
+	return tlsquery.ParsePrivateKeyFile(path, func() (string, error) {
+		return readPassword(keyPasswordEnv, "Enter password for "+path+": ")
+	})
+}
+
+func outputKey(key *tlsquery.KeyInfo, format string) error {
+	if format != "text" {
+		return encodeOutput(key, format)
+	}
+
+	fmt.Println("[PRIVATE KEY]")
+	fmt.Printf("Format:                %s\n", key.Format)
+	if key.Encryption != "" {
+		fmt.Printf("Encryption:            %s\n", key.Encryption)
+	}
+	printPublicKey(key.Algorithm, key.PublicKey)
+	return nil
+}
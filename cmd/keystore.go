@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tlsctl/internal/jks"
+	"github.com/tlsctl/internal/tlsquery"
+)
+
+// keystorePasswordEnv holds the key store password for non-interactive use.
+const keystorePasswordEnv = "TLSCTL_KEYSTORE_PASSWORD"
+
+var keystoreOutputFormat string
+var keystoreShowPEM bool
+var keystorePassword string
+
+var keystoreCmd = &cobra.Command{
+	Use:     "keystore FILE",
+	Aliases: []string{"jks"},
+	Short:   "Inspect the entries of a Java key store (JKS or JCEKS)",
+	Long: `Reads a Java key store or trust store (JKS or JCEKS), verifies its integrity
+with the store password and lists every alias with its entry type
+(trustedCert, privateKey or secretKey), creation date and certificates.
+Listing stops after the first secret key entry of a JCEKS store, as its
+serialized Java object cannot be skipped.
+
+The password is taken from --password, the ` + keystorePasswordEnv + ` environment
+variable, a terminal prompt, or the first line of stdin, in that order. An empty
+password lists the entries without checking the store's integrity.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runKeystore,
+}
+
+func init() {
+	rootCmd.AddCommand(keystoreCmd)
+	keystoreCmd.Flags().StringVarP(&keystoreOutputFormat, "output", "o", "text", "Output format (text, json, yaml)")
+	keystoreCmd.Flags().BoolVar(&keystoreShowPEM, "show-pem", false, "Include PEM-encoded certificates in output")
+	keystoreCmd.Flags().StringVar(&keystorePassword, "password", "", "Store password (visible in the process list, prefer "+keystorePasswordEnv+")")
+}
+
+func runKeystore(cmd *cobra.Command, args []string) error {
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	password := keystorePassword
+	if !cmd.Flags().Changed("password") {
+		if password, err = readPassword(keystorePasswordEnv, "Enter keystore password for "+args[0]+": "); err != nil {
+			return err
+		}
+	}
+
+	store, err := jks.Decode(data, password)
+	if err != nil {
+		return fmt.Errorf("failed to decode %s: %w", args[0], err)
+	}
+
+	info := tlsquery.KeyStoreInfoFromStore(store)
+	if !keystoreShowPEM {
+		for _, entry := range info.Entries {
+			for i := range entry.Certificates {
+				entry.Certificates[i].PEM = ""
+			}
+		}
+	}
+	return outputKeystore(info, keystoreOutputFormat)
+}
+
+func outputKeystore(info *tlsquery.KeyStoreInfo, format string) error {
+	if format != "text" {
+		return encodeOutput(info, format)
+	}
+
+	fmt.Printf("Keystore Type:         %s (version %d)\n", info.Type, info.Version)
+	fmt.Printf("Integrity Verified:    %t\n", info.IntegrityVerified)
+	fmt.Printf("Entries:               %d\n", len(info.Entries))
+	if info.Partial {
+		fmt.Printf("Partial:               %s\n", info.Note)
+	}
+
+	for i, entry := range info.Entries {
+		fmt.Println()
+		fmt.Printf("[ENTRY %d]\n", i+1)
+		fmt.Printf("Alias:                 %s\n", entry.Alias)
+		fmt.Printf("Entry Type:            %s\n", entry.Type)
+		fmt.Printf("Created:               %s\n", entry.Created)
+		for _, cert := range entry.Certificates {
+			fmt.Println()
+			fmt.Printf("[%s]\n", strings.ToUpper(cert.Type))
+			printCertInfo(&cert)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tlsctl/internal/tlsquery"
+)
+
+var lintFile string
+var lintOutputFormat string
+var lintFailOn string
+var lintStartTLS string
+var lintTimeout time.Duration
+
+var lintCmd = &cobra.Command{
+	Use:   "lint [FQDN[:PORT]]",
+	Short: "Check certificates against CA/B Forum and RFC 5280 rules",
+	Long: `Runs a built-in rule set over every certificate served by an endpoint or
+stored in a PEM file. Each finding has a rule ID, a severity (error or warning)
+and a citation of the requirement it enforces.
+
+Exits with 1 when a finding is at or above the --fail-on severity, so CI jobs
+can fail on lint errors.`,
+	Args:         cobra.MaximumNArgs(1),
+	RunE:         runLint,
+	SilenceUsage: true,
+}
+
+func init() {
+	rootCmd.AddCommand(lintCmd)
+	lintCmd.Flags().StringVarP(&lintFile, "file", "f", "", "Lint certificates from a PEM file instead of an endpoint")
+	lintCmd.Flags().StringVarP(&lintOutputFormat, "output", "o", "text", "Output format (text, json, yaml)")
+	lintCmd.Flags().StringVar(&lintFailOn, "fail-on", tlsquery.SeverityError, "Lowest severity that makes the command fail (error, warning, none)")
+	lintCmd.Flags().DurationVar(&lintTimeout, "timeout", 10*time.Second, "Timeout for connect and handshake (0 disables)")
+	lintCmd.Flags().StringVar(&lintStartTLS, "starttls", "", "Upgrade a plaintext connection before the handshake ("+strings.Join(tlsquery.StartTLSProtocols(), ", ")+")")
+}
+
+func runLint(cmd *cobra.Command, args []string) error {
+	switch lintFailOn {
+	case tlsquery.SeverityError, tlsquery.SeverityWarning, "none":
+	default:
+		return fmt.Errorf("invalid --fail-on: %q (valid: error, warning, none)", lintFailOn)
+	}
+
+	var chain *tlsquery.ChainInfo
+	var err error
+	switch {
+	case lintFile != "" && len(args) > 0:
+		return fmt.Errorf("specify either an endpoint or --file, not both")
+	case lintFile != "":
+		chain, err = tlsquery.ParsePEMFile(lintFile)
+	case len(args) == 1:
+		var endpoint string
+		endpoint, err = normalizeEndpoint(args[0], tlsquery.DefaultPort(lintStartTLS))
+		if err == nil {
+			opts := tlsquery.QueryOptions{StartTLS: lintStartTLS, Timeout: lintTimeout}
+			chain, err = tlsquery.QueryContext(cmd.Context(), endpoint, opts)
+		}
+	default:
+		return fmt.Errorf("an endpoint or --file is required")
+	}
+	if err != nil {
+		return err
+	}
+
+	report := tlsquery.LintChain(chain)
+	if err := outputLintReport(report, lintOutputFormat); err != nil {
+		return err
+	}
+
+	if report.Errors > 0 && lintFailOn != "none" || report.Warnings > 0 && lintFailOn == tlsquery.SeverityWarning {
+		cmd.SilenceErrors = true
+		return &exitCodeError{code: 1}
+	}
+	return nil
+}
+
+func outputLintReport(report *tlsquery.LintReport, format string) error {
+	if format != "text" {
+		return encodeOutput(report, format)
+	}
+
+	for i, result := range report.Certificates {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("[%s]\n", strings.ToUpper(result.Type))
+		fmt.Printf("Subject:               %s\n", result.Subject)
+		if len(result.Findings) == 0 {
+			fmt.Printf("Findings:              none\n")
+			continue
+		}
+		for _, f := range result.Findings {
+			fmt.Printf("%-8s %s: %s (%s)\n", strings.ToUpper(f.Severity), f.RuleID, f.Message, f.Citation)
+		}
+	}
+
+	fmt.Println()
+	fmt.Println("[SUMMARY]")
+	fmt.Printf("Errors:                %d\n", report.Errors)
+	fmt.Printf("Warnings:              %d\n", report.Warnings)
+	return nil
+}
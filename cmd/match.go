@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tlsctl/internal/tlsquery"
+)
+
+var matchCertFile string
+var matchKeyFile string
+var matchEndpoint string
+var matchOutputFormat string
+var matchServerName string
+var matchConnectTo string
+var matchProxy string
+var matchStartTLS string
+var matchTimeout time.Duration
+
+var matchCmd = &cobra.Command{
+	Use:   "match --key FILE (--cert FILE | --endpoint FQDN[:PORT])",
+	Short: "Check that a private key belongs to a certificate",
+	Long: `Compares the public key of a private key with the leaf certificate of a
+certificate file or of the chain served by an endpoint. Exits with an error
+when they do not match.`,
+	Args:         cobra.NoArgs,
+	RunE:         runMatch,
+	SilenceUsage: true,
+}
+
+func init() {
+	rootCmd.AddCommand(matchCmd)
+	matchCmd.Flags().StringVar(&matchCertFile, "cert", "", "Certificate file (PEM, DER or PKCS#7)")
+	matchCmd.Flags().StringVar(&matchKeyFile, "key", "", "Private key file")
+	matchCmd.Flags().StringVar(&matchEndpoint, "endpoint", "", "Compare with the certificate served by this endpoint")
+	matchCmd.Flags().StringVarP(&matchOutputFormat, "output", "o", "text", "Output format (text, json, yaml)")
+	matchCmd.Flags().StringVar(&matchServerName, "servername", "", "Server name to send as SNI for --endpoint (default: endpoint host)")
+	matchCmd.Flags().StringVar(&matchConnectTo, "connect-to", "", "Connect to HOST[:PORT] instead of --endpoint, keeping the endpoint host as SNI")
+	matchCmd.Flags().StringVar(&matchProxy, "proxy", "", "Connect through a proxy (http://, socks5:// or socks5h:// URL)")
+	matchCmd.Flags().DurationVar(&matchTimeout, "timeout", 10*time.Second, "Timeout for connect and handshake (0 disables)")
+	matchCmd.Flags().StringVar(&matchStartTLS, "starttls", "", "Upgrade a plaintext connection before the handshake ("+strings.Join(tlsquery.StartTLSProtocols(), ", ")+")")
+	matchCmd.MarkFlagRequired("key")
+}
+
+func runMatch(cmd *cobra.Command, args []string) error {
+	if (matchCertFile == "") == (matchEndpoint == "") {
+		return errors.New("specify either --cert or --endpoint")
+	}
+
+	key, err := loadPrivateKey(matchKeyFile)
+	if err != nil {
+		return err
+	}
+
+	var chain *tlsquery.ChainInfo
+	if matchCertFile != "" {
+		chain, err = tlsquery.ParsePEMFile(matchCertFile)
+	} else {
+		chain, err = queryMatchEndpoint(cmd.Context())
+	}
+	if err != nil {
+		return err
+	}
+
+	match := tlsquery.MatchKey(key, chain)
+	if matchOutputFormat != "text" {
+		if err := encodeOutput(match, matchOutputFormat); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("Certificate:           %s\n", match.CertificateSubject)
+		fmt.Printf("Certificate SPKI:      %s\n", match.CertificateSPKISHA256)
+		fmt.Printf("Key SPKI:              %s\n", match.KeySPKISHA256)
+		fmt.Printf("Match:                 %t\n", match.Match)
+	}
+	if !match.Match {
+		return errors.New("private key does not match the certificate")
+	}
+	return nil
+}
+
+// queryMatchEndpoint fetches the chain served by --endpoint with the same
+// connection options as client.
+func queryMatchEndpoint(ctx context.Context) (*tlsquery.ChainInfo, error) {
+	endpoint, err := normalizeEndpoint(matchEndpoint, tlsquery.DefaultPort(matchStartTLS))
+	if err != nil {
+		return nil, err
+	}
+
+	opts := tlsquery.QueryOptions{
+		ServerName: matchServerName,
+		StartTLS:   matchStartTLS,
+		Timeout:    matchTimeout,
+	}
+	if matchConnectTo != "" {
+		endpoint, opts.ServerName, err = connectToEndpoint(endpoint, matchConnectTo, matchServerName)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if opts.Proxy, err = parseProxy(matchProxy); err != nil {
+		return nil, err
+	}
+	return tlsquery.QueryContext(ctx, endpoint, opts)
+}
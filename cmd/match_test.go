@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestQueryMatchEndpoint_ConnectToTimeout(t *testing.T) {
+	// The listener accepts connections but never answers the handshake.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	savedEndpoint, savedConnectTo, savedTimeout := matchEndpoint, matchConnectTo, matchTimeout
+	matchEndpoint = "www.example.com"
+	matchConnectTo = listener.Addr().String()
+	matchTimeout = 100 * time.Millisecond
+	defer func() {
+		matchEndpoint, matchConnectTo, matchTimeout = savedEndpoint, savedConnectTo, savedTimeout
+	}()
+
+	start := time.Now()
+	if _, err := queryMatchEndpoint(context.Background()); err == nil {
+		t.Fatal("expected error for stalled handshake")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("query took %v, expected --timeout to apply", elapsed)
+	}
+}
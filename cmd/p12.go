@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/tlsctl/internal/pkcs12"
+	"github.com/tlsctl/internal/tlsquery"
+)
+
+var p12OutputFormat string
+var p12ShowPEM bool
+var p12Password string
+
+var p12Cmd = &cobra.Command{
+	Use:     "p12 FILE",
+	Aliases: []string{"pfx"},
+	Short:   "Inspect the certificates and keys in a PKCS#12 file",
+	Long: `Reads a PKCS#12 (.p12/.pfx) file and lists its certificate and key bags
+with their friendly names, local key IDs and encryption, the MAC algorithm and
+iteration count, and the certificate each private key belongs to.
+
+The password is taken from --password, the ` + p12PasswordEnv + ` environment
+variable, a terminal prompt, or the first line of stdin, in that order.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runP12,
+}
+
+func init() {
+	rootCmd.AddCommand(p12Cmd)
+	p12Cmd.Flags().StringVarP(&p12OutputFormat, "output", "o", "text", "Output format (text, json, yaml)")
+	p12Cmd.Flags().BoolVar(&p12ShowPEM, "show-pem", false, "Include PEM-encoded certificates in output")
+	p12Cmd.Flags().StringVar(&p12Password, "password", "", "Password of the file (visible in the process list, prefer "+p12PasswordEnv+")")
+}
+
+func runP12(cmd *cobra.Command, args []string) error {
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	password := p12Password
+	if !cmd.Flags().Changed("password") {
+		if password, err = readPassword(p12PasswordEnv, "Enter password for "+args[0]+": "); err != nil {
+			return err
+		}
+	}
+
+	store, err := pkcs12.Decode(data, password)
+	if err != nil {
+		return fmt.Errorf("failed to decode %s: %w", args[0], err)
+	}
+
+	info := tlsquery.PKCS12InfoFromStore(store)
+	if !p12ShowPEM {
+		for _, bag := range info.Bags {
+			if bag.Certificate != nil {
+				bag.Certificate.PEM = ""
+			}
+		}
+	}
+	return outputP12(info, p12OutputFormat)
+}
+
+func outputP12(info *tlsquery.PKCS12Info, format string) error {
+	if format != "text" {
+		return encodeOutput(info, format)
+	}
+
+	fmt.Println("[MAC]")
+	if info.MAC == nil {
+		fmt.Printf("Algorithm:             none\n")
+	} else {
+		fmt.Printf("Algorithm:             %s\n", info.MAC.Algorithm)
+		fmt.Printf("Iterations:            %d\n", info.MAC.Iterations)
+		fmt.Printf("Salt Length:           %d\n", info.MAC.SaltLength)
+	}
+
+	for i, bag := range info.Bags {
+		fmt.Println()
+		fmt.Printf("[BAG %d]\n", i+1)
+		fmt.Printf("Bag Type:              %s\n", bag.Type)
+		if bag.FriendlyName != "" {
+			fmt.Printf("Friendly Name:         %s\n", bag.FriendlyName)
+		}
+		if bag.LocalKeyID != "" {
+			fmt.Printf("Local Key ID:          %s\n", bag.LocalKeyID)
+		}
+		if bag.Encryption != "" {
+			fmt.Printf("Encryption:            %s, %d iterations\n", bag.Encryption, bag.EncryptionIterations)
+		} else {
+			fmt.Printf("Encryption:            none\n")
+		}
+		if key := bag.PrivateKey; key != nil {
+			fmt.Printf("Private Key:           %s\n", key.Algorithm)
+			if key.CertificateBag > 0 {
+				fmt.Printf("Certificate:           bag %d (%s)\n", key.CertificateBag, key.CertificateSubject)
+			} else {
+				fmt.Printf("Certificate:           none found\n")
+			}
+		}
+		if cert := bag.Certificate; cert != nil {
+			fmt.Printf("Certificate Type:      %s\n", cert.Type)
+			printCertInfo(cert)
+		}
+	}
+	return nil
+}
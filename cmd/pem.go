@@ -1,25 +1,46 @@
 package cmd
 
 import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
 	"github.com/spf13/cobra"
 	"github.com/tlsctl/internal/tlsquery"
 )
 
 var pemOutputFormat string
 var pemShowPEM bool
+var pemCheckOCSP bool
+var pemCheckCRL bool
+var pemCRLFiles []string
+var pemCTLogList string
+var pemNoAIA bool
+var pemFixOrder bool
 
 var pemCmd = &cobra.Command{
 	Use:   "pem FILE",
-	Short: "Parse and display certificates from a PEM file",
-	Long:  `Reads a PEM file and displays certificate metadata for all certificates found.`,
-	Args:  cobra.ExactArgs(1),
-	RunE:  runPem,
+	Short: "Parse and display certificates from a PEM, DER or PKCS#7 file",
+	Long: `Reads a PEM file and displays certificate metadata for all certificates found.
+DER certificates (.cer/.der), PKCS#7 bundles (.p7b/.p7c, PEM or DER) and
+OpenSSL TRUSTED CERTIFICATE blocks are detected automatically. PEM blocks that
+hold no certificates are listed as skipped.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runPem,
 }
 
 func init() {
 	rootCmd.AddCommand(pemCmd)
-	pemCmd.Flags().StringVarP(&pemOutputFormat, "output", "o", "text", "Output format (text, json, yaml)")
+	pemCmd.Flags().StringVarP(&pemOutputFormat, "output", "o", "text", "Output format (text, json, yaml, pem)")
 	pemCmd.Flags().BoolVar(&pemShowPEM, "show-pem", false, "Include PEM-encoded certificate in output")
+	pemCmd.Flags().BoolVar(&pemCheckOCSP, "check-ocsp", false, "Check the revocation status of each certificate with its OCSP responder")
+	pemCmd.Flags().BoolVar(&pemCheckCRL, "check-crl", false, "Check the revocation status of each certificate against its CRL distribution point")
+	pemCmd.Flags().BoolVar(&pemNoAIA, "no-aia", false, "Do not fetch missing intermediates from AIA caIssuers URLs")
+	pemCmd.Flags().StringVar(&pemCTLogList, "ct-logs", "", "CT log list JSON (v3 schema) to verify embedded SCT signatures against")
+	pemCmd.Flags().BoolVar(&pemFixOrder, "fix-order", false, "Reorder the chain leaf to root, dropping duplicate and unrelated certificates")
+	pemCmd.Flags().StringSliceVar(&pemCRLFiles, "crl", nil, "Local CRL file (PEM or DER) to check against instead of downloading (implies --check-crl)")
 }
 
 func runPem(cmd *cobra.Command, args []string) error {
@@ -27,6 +48,48 @@ func runPem(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	if pemFixOrder {
+		for _, issue := range chainInfo.Analysis.Issues {
+			fmt.Fprintf(os.Stderr, "Warning: %s: %s\n", issue.Type, issue.Message)
+		}
+		chainInfo = tlsquery.OrderChain(chainInfo)
+	}
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+	if !pemNoAIA {
+		tlsquery.CompleteChain(cmd.Context(), chainInfo, httpClient, nil)
+	}
+	if pemCheckOCSP {
+		tlsquery.CheckOCSP(cmd.Context(), chainInfo, httpClient)
+	}
+	if pemCheckCRL || len(pemCRLFiles) > 0 {
+		if err := tlsquery.CheckCRL(cmd.Context(), chainInfo, httpClient, pemCRLFiles); err != nil {
+			return err
+		}
+	}
+	if pemCTLogList != "" {
+		logs, err := tlsquery.LoadCTLogList(pemCTLogList)
+		if err != nil {
+			return err
+		}
+		tlsquery.VerifySCTs(chainInfo, logs)
+	}
 
+	if pemOutputFormat == "pem" {
+		writeBundle(os.Stdout, os.Stderr, chainInfo.Certificates)
+		return nil
+	}
 	return outputChain(chainInfo, pemOutputFormat, pemShowPEM)
 }
+
+// writeBundle writes the PEM of certs to w. Fetched issuers are not part of
+// the bundle that was read, so they are left out of a bundle meant for
+// deployment and named in a warning on warn instead.
+func writeBundle(w, warn io.Writer, certs []tlsquery.CertInfo) {
+	for _, cert := range certs {
+		if cert.Source == tlsquery.SourceFetched {
+			fmt.Fprintf(warn, "Warning: omitting issuer %s fetched via AIA, the bundle does not include it\n", cert.Subject)
+			continue
+		}
+		fmt.Fprint(w, cert.PEM)
+	}
+}
@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/tlsctl/internal/tlsquery"
+)
+
+func TestWriteBundle(t *testing.T) {
+	certs := []tlsquery.CertInfo{
+		{Subject: "CN=leaf", Source: tlsquery.SourceFile, PEM: "leaf\n"},
+		{Subject: "CN=intermediate", Source: tlsquery.SourceFile, PEM: "intermediate\n"},
+		{Subject: "CN=fetched", Source: tlsquery.SourceFetched, PEM: "fetched\n"},
+	}
+
+	var out, warn bytes.Buffer
+	writeBundle(&out, &warn, certs)
+
+	if out.String() != "leaf\nintermediate\n" {
+		t.Errorf("bundle = %q, want only the certificates from the file", out.String())
+	}
+	if !strings.Contains(warn.String(), "CN=fetched") {
+		t.Errorf("expected a warning naming the fetched issuer, got %q", warn.String())
+	}
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tlsctl/internal/tlsquery"
+)
+
+var scanOutputFormat string
+var scanStartTLS string
+var scanTimeout time.Duration
+
+var scanCmd = &cobra.Command{
+	Use:   "scan FQDN[:PORT]",
+	Short: "Enumerate the TLS versions and cipher suites accepted by an endpoint",
+	Long: `Connects repeatedly to a TLS endpoint with constrained settings and reports
+every TLS version and cipher suite the server accepts, in the order the server
+selects them. Whether that order is the server's own preference is tested by
+offering the accepted suites in reverse.
+
+Only cipher suites implemented by Go's crypto/tls can be detected, and only the
+negotiated TLS 1.3 suite is listed.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runScan,
+}
+
+func init() {
+	rootCmd.AddCommand(scanCmd)
+	scanCmd.Flags().StringVarP(&scanOutputFormat, "output", "o", "text", "Output format (text, json, yaml)")
+	scanCmd.Flags().DurationVar(&scanTimeout, "timeout", 10*time.Second, "Timeout for each connect and handshake (0 disables)")
+	scanCmd.Flags().StringVar(&scanStartTLS, "starttls", "", "Upgrade a plaintext connection before the handshake ("+strings.Join(tlsquery.StartTLSProtocols(), ", ")+")")
+}
+
+func runScan(cmd *cobra.Command, args []string) error {
+	endpoint, err := normalizeEndpoint(args[0], tlsquery.DefaultPort(scanStartTLS))
+	if err != nil {
+		return err
+	}
+
+	result, err := tlsquery.Scan(cmd.Context(), endpoint, tlsquery.QueryOptions{StartTLS: scanStartTLS, Timeout: scanTimeout})
+	if err != nil {
+		return err
+	}
+
+	return outputScan(result, scanOutputFormat)
+}
+
+func outputScan(result *tlsquery.ScanResult, format string) error {
+	if format != "text" {
+		return encodeOutput(result, format)
+	}
+
+	fmt.Printf("Endpoint:              %s\n", result.Endpoint)
+	for _, v := range result.Versions {
+		fmt.Println()
+		if !v.Supported {
+			fmt.Printf("[%s] not supported\n", v.Version)
+			continue
+		}
+		order := ""
+		if v.ServerPreference != nil {
+			if *v.ServerPreference {
+				order = ", server preference order"
+			} else {
+				order = ", client preference order"
+			}
+		}
+		fmt.Printf("[%s] supported%s\n", v.Version, order)
+		for _, cs := range v.CipherSuites {
+			marker := ""
+			if cs.Insecure {
+				marker = "  (insecure)"
+			}
+			fmt.Printf("  %-6s  %s%s\n", cs.ID, cs.Name, marker)
+		}
+		if v.Partial {
+			fmt.Printf("  Partial: %s\n", v.Note)
+		}
+	}
+	return nil
+}
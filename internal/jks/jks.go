@@ -0,0 +1,246 @@
+// Package jks decodes Java KeyStore (JKS) and JCEKS files as written by
+// keytool and java.security.KeyStore. Entries are listed with their
+// certificates; private keys are left encrypted.
+package jks
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+	"unicode/utf16"
+)
+
+// Store types.
+const (
+	TypeJKS   = "JKS"
+	TypeJCEKS = "JCEKS"
+)
+
+// Entry types, as named by keytool.
+const (
+	PrivateKeyEntry  = "privateKey"
+	TrustedCertEntry = "trustedCert"
+	SecretKeyEntry   = "secretKey"
+)
+
+const (
+	magicJKS   = 0xfeedfeed
+	magicJCEKS = 0xcececece
+
+	tagPrivateKey  = 1
+	tagTrustedCert = 2
+	tagSecretKey   = 3
+)
+
+// ErrIncorrectPassword is returned when the integrity check fails.
+var ErrIncorrectPassword = errors.New("jks: keystore password was incorrect")
+
+// errSecretKey stops decoding at a secret key entry.
+var errSecretKey = errors.New("jks: secret key entries are not supported")
+
+// KeyStore is a decoded key store.
+type KeyStore struct {
+	Type    string
+	Version int
+	// Verified reports whether the integrity digest was checked. It is
+	// false when Decode was called with an empty password.
+	Verified bool
+	Entries  []Entry
+	// Partial reports that decoding stopped before the last entry, and Note
+	// explains why.
+	Partial bool
+	Note    string
+}
+
+// Entry is a single aliased entry of a key store.
+type Entry struct {
+	Alias   string
+	Type    string
+	Created time.Time
+	// Certificates holds the trusted certificate, or the certificate chain
+	// of a private key, leaf first. It is empty for secret keys.
+	Certificates []*x509.Certificate
+}
+
+// Decode parses a JKS or JCEKS key store. The integrity digest is verified
+// with password unless password is empty. Decoding stops after the first
+// secret key entry, which is listed without its key, and the store is
+// marked as partial.
+func Decode(data []byte, password string) (*KeyStore, error) {
+	if len(data) < 12+sha1.Size {
+		return nil, errors.New("jks: file too short")
+	}
+	body, digest := data[:len(data)-sha1.Size], data[len(data)-sha1.Size:]
+
+	r := &reader{data: body}
+	store := &KeyStore{}
+	switch r.uint32() {
+	case magicJKS:
+		store.Type = TypeJKS
+	case magicJCEKS:
+		store.Type = TypeJCEKS
+	default:
+		return nil, errors.New("jks: not a JKS or JCEKS key store")
+	}
+	store.Version = int(r.uint32())
+	if store.Version != 1 && store.Version != 2 {
+		return nil, fmt.Errorf("jks: unsupported version %d", store.Version)
+	}
+
+	if password != "" {
+		if subtle.ConstantTimeCompare(integrityDigest(body, password), digest) != 1 {
+			return nil, ErrIncorrectPassword
+		}
+		store.Verified = true
+	}
+
+	count := r.uint32()
+	for i := uint32(0); i < count && r.err == nil; i++ {
+		entry, err := r.entry(store)
+		if err == errSecretKey {
+			store.Entries = append(store.Entries, entry)
+			store.Partial = true
+			store.Note = fmt.Sprintf("stopped at secret key entry %q, which cannot be skipped; %d of %d entries listed",
+				entry.Alias, i+1, count)
+			return store, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		store.Entries = append(store.Entries, entry)
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("jks: invalid key store: %w", r.err)
+	}
+	if r.off != len(body) {
+		return nil, errors.New("jks: invalid key store: trailing data")
+	}
+	return store, nil
+}
+
+// integrityDigest computes the keytool integrity digest: SHA-1 over the
+// UTF-16BE password, the string "Mighty Aphrodite" and the store contents.
+func integrityDigest(body []byte, password string) []byte {
+	h := sha1.New()
+	for _, c := range utf16.Encode([]rune(password)) {
+		h.Write([]byte{byte(c >> 8), byte(c)})
+	}
+	h.Write([]byte("Mighty Aphrodite"))
+	h.Write(body)
+	return h.Sum(nil)
+}
+
+// reader decodes the big-endian Java DataOutputStream encoding. The first
+// error is kept in err and later reads return zero values.
+type reader struct {
+	data []byte
+	off  int
+	err  error
+}
+
+func (r *reader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.data)-r.off {
+		r.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	b := r.data[r.off : r.off+n]
+	r.off += n
+	return b
+}
+
+func (r *reader) uint16() uint16 {
+	b := r.bytes(2)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint16(b)
+}
+
+func (r *reader) uint32() uint32 {
+	b := r.bytes(4)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+func (r *reader) uint64() uint64 {
+	b := r.bytes(8)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint64(b)
+}
+
+// utf reads a string written by DataOutputStream.writeUTF. Aliases are
+// plain text, so the modified UTF-8 encoding is read as UTF-8.
+func (r *reader) utf() string {
+	return string(r.bytes(int(r.uint16())))
+}
+
+func (r *reader) entry(store *KeyStore) (Entry, error) {
+	tag := r.uint32()
+	entry := Entry{Alias: r.utf()}
+	entry.Created = time.UnixMilli(int64(r.uint64())).UTC()
+
+	switch tag {
+	case tagPrivateKey:
+		entry.Type = PrivateKeyEntry
+		r.bytes(int(r.uint32())) // encrypted private key
+		chainLength := r.uint32()
+		for i := uint32(0); i < chainLength && r.err == nil; i++ {
+			cert, err := r.certificate(store.Version)
+			if err != nil {
+				return entry, fmt.Errorf("jks: entry %q: %w", entry.Alias, err)
+			}
+			entry.Certificates = append(entry.Certificates, cert)
+		}
+	case tagTrustedCert:
+		entry.Type = TrustedCertEntry
+		cert, err := r.certificate(store.Version)
+		if err != nil {
+			return entry, fmt.Errorf("jks: entry %q: %w", entry.Alias, err)
+		}
+		entry.Certificates = []*x509.Certificate{cert}
+	case tagSecretKey:
+		// Secret keys are serialized Java objects, which cannot be
+		// skipped without a Java deserializer.
+		entry.Type = SecretKeyEntry
+		if r.err == nil {
+			return entry, errSecretKey
+		}
+	default:
+		if r.err == nil {
+			r.err = fmt.Errorf("unknown entry tag %d", tag)
+		}
+	}
+	return entry, nil
+}
+
+func (r *reader) certificate(version int) (*x509.Certificate, error) {
+	certType := "X.509"
+	if version == 2 {
+		certType = r.utf()
+	}
+	der := r.bytes(int(r.uint32()))
+	if r.err != nil {
+		return nil, nil
+	}
+	if certType != "X.509" {
+		return nil, fmt.Errorf("unsupported certificate type %q", certType)
+	}
+	cert, err := x509.ParseCertificate(bytes.Clone(der))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate: %w", err)
+	}
+	return cert, nil
+}
@@ -0,0 +1,202 @@
+package jks
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newCertificate(t *testing.T, cn string) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	return cert
+}
+
+// writer encodes key stores the way java.security.KeyStore does.
+type writer struct {
+	bytes.Buffer
+	version int
+}
+
+func (w *writer) uint32(v uint32) { binary.Write(w, binary.BigEndian, v) }
+func (w *writer) uint64(v uint64) { binary.Write(w, binary.BigEndian, v) }
+
+func (w *writer) utf(s string) {
+	binary.Write(w, binary.BigEndian, uint16(len(s)))
+	w.WriteString(s)
+}
+
+func (w *writer) certificate(cert *x509.Certificate) {
+	if w.version == 2 {
+		w.utf("X.509")
+	}
+	w.uint32(uint32(len(cert.Raw)))
+	w.Write(cert.Raw)
+}
+
+func (w *writer) header(magic uint32, count int) {
+	w.uint32(magic)
+	w.uint32(uint32(w.version))
+	w.uint32(uint32(count))
+}
+
+func (w *writer) entry(tag uint32, alias string, created time.Time) {
+	w.uint32(tag)
+	w.utf(alias)
+	w.uint64(uint64(created.UnixMilli()))
+}
+
+func (w *writer) finish(password string) []byte {
+	return append(w.Bytes(), integrityDigest(w.Bytes(), password)...)
+}
+
+func TestDecode(t *testing.T) {
+	root := newCertificate(t, "Test Root")
+	leaf := newCertificate(t, "server")
+	created := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, tt := range []struct {
+		name     string
+		magic    uint32
+		version  int
+		wantType string
+	}{
+		{"JKS v2", magicJKS, 2, TypeJKS},
+		{"JKS v1", magicJKS, 1, TypeJKS},
+		{"JCEKS", magicJCEKS, 2, TypeJCEKS},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &writer{version: tt.version}
+			w.header(tt.magic, 2)
+			w.entry(tagTrustedCert, "root", created)
+			w.certificate(root)
+			w.entry(tagPrivateKey, "server", created)
+			w.uint32(4)
+			w.WriteString("key!")
+			w.uint32(2)
+			w.certificate(leaf)
+			w.certificate(root)
+			data := w.finish("changeit")
+
+			store, err := Decode(data, "changeit")
+			if err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+			if store.Type != tt.wantType || store.Version != tt.version || !store.Verified {
+				t.Errorf("unexpected store %s v%d (verified %t)", store.Type, store.Version, store.Verified)
+			}
+			if len(store.Entries) != 2 {
+				t.Fatalf("expected 2 entries, got %d", len(store.Entries))
+			}
+
+			trusted := store.Entries[0]
+			if trusted.Alias != "root" || trusted.Type != TrustedCertEntry || !trusted.Created.Equal(created) {
+				t.Errorf("unexpected entry %q (%s, %s)", trusted.Alias, trusted.Type, trusted.Created)
+			}
+			if len(trusted.Certificates) != 1 || !trusted.Certificates[0].Equal(root) {
+				t.Error("expected the root certificate")
+			}
+
+			key := store.Entries[1]
+			if key.Alias != "server" || key.Type != PrivateKeyEntry {
+				t.Errorf("unexpected entry %q (%s)", key.Alias, key.Type)
+			}
+			if len(key.Certificates) != 2 || !key.Certificates[0].Equal(leaf) || !key.Certificates[1].Equal(root) {
+				t.Error("expected the leaf and root certificates")
+			}
+
+			store, err = Decode(data, "")
+			if err != nil {
+				t.Fatalf("Decode without password failed: %v", err)
+			}
+			if store.Verified || len(store.Entries) != 2 {
+				t.Errorf("expected 2 unverified entries, got %d (verified %t)", len(store.Entries), store.Verified)
+			}
+		})
+	}
+}
+
+func TestDecode_IncorrectPassword(t *testing.T) {
+	w := &writer{version: 2}
+	w.header(magicJKS, 0)
+	if _, err := Decode(w.finish("changeit"), "wrong"); !errors.Is(err, ErrIncorrectPassword) {
+		t.Errorf("expected ErrIncorrectPassword, got %v", err)
+	}
+}
+
+func TestDecode_SecretKey(t *testing.T) {
+	root := newCertificate(t, "Test Root")
+	created := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	w := &writer{version: 2}
+	w.header(magicJCEKS, 3)
+	w.entry(tagTrustedCert, "root", created)
+	w.certificate(root)
+	w.entry(tagSecretKey, "aes", created)
+	w.WriteString("\xac\xed\x00\x05sr") // start of a serialized Java object
+	store, err := Decode(w.finish("changeit"), "changeit")
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if !store.Verified || !store.Partial {
+		t.Errorf("expected a verified partial store (verified %t, partial %t)", store.Verified, store.Partial)
+	}
+	if len(store.Entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(store.Entries))
+	}
+	secret := store.Entries[1]
+	if secret.Alias != "aes" || secret.Type != SecretKeyEntry || !secret.Created.Equal(created) || len(secret.Certificates) != 0 {
+		t.Errorf("unexpected entry %q (%s, %s)", secret.Alias, secret.Type, secret.Created)
+	}
+	if !strings.Contains(store.Note, `"aes"`) || !strings.Contains(store.Note, "2 of 3 entries") {
+		t.Errorf("unexpected note %q", store.Note)
+	}
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	truncated := &writer{version: 2}
+	truncated.header(magicJKS, 1)
+	truncated.entry(tagTrustedCert, "root", time.Now())
+
+	for _, tt := range []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"not a key store", bytes.Repeat([]byte{0x30}, 64), "not a JKS"},
+		{"truncated", truncated.finish("changeit"), "invalid key store"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode(tt.data, "changeit")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
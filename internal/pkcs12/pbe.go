@@ -0,0 +1,339 @@
+package pkcs12
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"hash"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+var (
+	oidSHA1   = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidSHA256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA384 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidSHA512 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+
+	oidHMACWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHMACWithSHA384 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 10}
+	oidHMACWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+
+	oidPBEWithSHAAnd3KeyTripleDESCBC = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 3}
+	oidPBEWithSHAAnd2KeyTripleDESCBC = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 4}
+	oidPBEWithSHAAnd128BitRC2CBC     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 5}
+	oidPBEWithSHAAnd40BitRC2CBC      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 6}
+
+	oidPBES2  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+
+	oidAES128CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	oidDESEDE3CBC = asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 7}
+)
+
+type digest struct {
+	name      string
+	new       func() hash.Hash
+	blockSize int
+}
+
+var macDigests = []struct {
+	oid asn1.ObjectIdentifier
+	digest
+}{
+	{oidSHA1, digest{"SHA-1", sha1.New, 64}},
+	{oidSHA256, digest{"SHA-256", sha256.New, 64}},
+	{oidSHA384, digest{"SHA-384", sha512.New384, 128}},
+	{oidSHA512, digest{"SHA-512", sha512.New, 128}},
+}
+
+var prfDigests = []struct {
+	oid asn1.ObjectIdentifier
+	digest
+}{
+	{oidHMACWithSHA1, digest{"HMAC-SHA1", sha1.New, 64}},
+	{oidHMACWithSHA256, digest{"HMAC-SHA256", sha256.New, 64}},
+	{oidHMACWithSHA384, digest{"HMAC-SHA384", sha512.New384, 128}},
+	{oidHMACWithSHA512, digest{"HMAC-SHA512", sha512.New, 128}},
+}
+
+// pbeScheme is one of the PKCS#12 password based encryption schemes of
+// RFC 7292 appendix C. All of them derive key and IV with SHA-1.
+type pbeScheme struct {
+	oid       asn1.ObjectIdentifier
+	name      string
+	keyLen    int
+	newCipher func(key []byte) (cipher.Block, error)
+	blockSize int
+}
+
+var pbeSchemes = []pbeScheme{
+	{oidPBEWithSHAAnd3KeyTripleDESCBC, "pbeWithSHAAnd3-KeyTripleDES-CBC", 24, des.NewTripleDESCipher, des.BlockSize},
+	{oidPBEWithSHAAnd2KeyTripleDESCBC, "pbeWithSHAAnd2-KeyTripleDES-CBC", 16, func(key []byte) (cipher.Block, error) {
+		return des.NewTripleDESCipher(append(append([]byte{}, key...), key[:8]...))
+	}, des.BlockSize},
+	{oidPBEWithSHAAnd128BitRC2CBC, "pbeWithSHAAnd128BitRC2-CBC", 16, func(key []byte) (cipher.Block, error) {
+		return newRC2Cipher(key, 128)
+	}, rc2BlockSize},
+	{oidPBEWithSHAAnd40BitRC2CBC, "pbeWithSHAAnd40BitRC2-CBC", 5, func(key []byte) (cipher.Block, error) {
+		return newRC2Cipher(key, 40)
+	}, rc2BlockSize},
+}
+
+var pbes2Ciphers = []struct {
+	oid    asn1.ObjectIdentifier
+	name   string
+	keyLen int
+	new    func(key []byte) (cipher.Block, error)
+}{
+	{oidAES128CBC, "AES-128-CBC", 16, aes.NewCipher},
+	{oidAES192CBC, "AES-192-CBC", 24, aes.NewCipher},
+	{oidAES256CBC, "AES-256-CBC", 32, aes.NewCipher},
+	{oidDESEDE3CBC, "DES-EDE3-CBC", 24, des.NewTripleDESCipher},
+}
+
+type pbeParams struct {
+	Salt       []byte
+	Iterations int
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt       []byte
+	Iterations int
+	KeyLength  int                      `asn1:"optional"`
+	PRF        pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// Key derivation purposes of RFC 7292 appendix B.3.
+const (
+	kdfKey = 1
+	kdfIV  = 2
+	kdfMAC = 3
+)
+
+// maxIterations bounds the key derivation work a file can demand. Real
+// stores use a few thousand iterations; a crafted count near 2^31 would
+// otherwise keep the decoder busy for hours.
+const maxIterations = 10000000
+
+func checkIterations(iterations int) error {
+	if iterations < 1 || iterations > maxIterations {
+		return fmt.Errorf("pkcs12: unsupported iteration count %d", iterations)
+	}
+	return nil
+}
+
+// verifyMAC checks the store MAC. An empty password is tried both as an
+// empty BMPString and as no password at all, since implementations
+// disagree on its encoding. The password encoding that matched is returned.
+func verifyMAC(md *macData, content, password []byte) (*MAC, []byte, error) {
+	var d *digest
+	for i := range macDigests {
+		if md.Mac.Algorithm.Algorithm.Equal(macDigests[i].oid) {
+			d = &macDigests[i].digest
+		}
+	}
+	if d == nil {
+		return nil, nil, fmt.Errorf("pkcs12: unsupported MAC algorithm %s", md.Mac.Algorithm.Algorithm)
+	}
+
+	if err := checkIterations(md.Iterations); err != nil {
+		return nil, nil, err
+	}
+
+	mac := &MAC{Algorithm: d.name, Iterations: md.Iterations, SaltLength: len(md.MacSalt)}
+	candidates := [][]byte{password}
+	if len(password) == 2 {
+		candidates = append(candidates, nil)
+	}
+	for _, p := range candidates {
+		key := pbkdf(*d, md.MacSalt, p, md.Iterations, kdfMAC, d.new().Size())
+		h := hmac.New(d.new, key)
+		h.Write(content)
+		if hmac.Equal(h.Sum(nil), md.Mac.Digest) {
+			return mac, p, nil
+		}
+	}
+	return nil, nil, ErrIncorrectPassword
+}
+
+// decrypt decrypts data protected by alg. bmpPassword is used by the
+// PKCS#12 schemes, password by PBES2.
+func decrypt(alg pkix.AlgorithmIdentifier, data []byte, password string, bmpPassword []byte) ([]byte, *Encryption, error) {
+	if alg.Algorithm.Equal(oidPBES2) {
+		return decryptPBES2(alg, data, []byte(password))
+	}
+
+	for _, scheme := range pbeSchemes {
+		if !alg.Algorithm.Equal(scheme.oid) {
+			continue
+		}
+		var params pbeParams
+		if err := unmarshal(alg.Parameters.FullBytes, &params); err != nil {
+			return nil, nil, fmt.Errorf("pkcs12: invalid %s parameters: %w", scheme.name, err)
+		}
+		if err := checkIterations(params.Iterations); err != nil {
+			return nil, nil, err
+		}
+		sha := digest{"SHA-1", sha1.New, 64}
+		key := pbkdf(sha, params.Salt, bmpPassword, params.Iterations, kdfKey, scheme.keyLen)
+		iv := pbkdf(sha, params.Salt, bmpPassword, params.Iterations, kdfIV, scheme.blockSize)
+		block, err := scheme.newCipher(key)
+		if err != nil {
+			return nil, nil, err
+		}
+		plain, err := decryptCBC(block, iv, data)
+		if err != nil {
+			return nil, nil, err
+		}
+		return plain, &Encryption{Algorithm: scheme.name, Iterations: params.Iterations}, nil
+	}
+
+	return nil, nil, fmt.Errorf("pkcs12: unsupported encryption algorithm %s", alg.Algorithm)
+}
+
+func decryptPBES2(alg pkix.AlgorithmIdentifier, data, password []byte) ([]byte, *Encryption, error) {
+	var params pbes2Params
+	if err := unmarshal(alg.Parameters.FullBytes, &params); err != nil {
+		return nil, nil, fmt.Errorf("pkcs12: invalid PBES2 parameters: %w", err)
+	}
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, nil, fmt.Errorf("pkcs12: unsupported PBES2 key derivation function %s", params.KeyDerivationFunc.Algorithm)
+	}
+	var kdf pbkdf2Params
+	if err := unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdf); err != nil {
+		return nil, nil, fmt.Errorf("pkcs12: invalid PBKDF2 parameters: %w", err)
+	}
+	if err := checkIterations(kdf.Iterations); err != nil {
+		return nil, nil, err
+	}
+
+	prf := &prfDigests[0].digest
+	if len(kdf.PRF.Algorithm) > 0 {
+		prf = nil
+		for i := range prfDigests {
+			if kdf.PRF.Algorithm.Equal(prfDigests[i].oid) {
+				prf = &prfDigests[i].digest
+			}
+		}
+		if prf == nil {
+			return nil, nil, fmt.Errorf("pkcs12: unsupported PBKDF2 PRF %s", kdf.PRF.Algorithm)
+		}
+	}
+
+	for _, c := range pbes2Ciphers {
+		if !params.EncryptionScheme.Algorithm.Equal(c.oid) {
+			continue
+		}
+		var iv []byte
+		if err := unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+			return nil, nil, fmt.Errorf("pkcs12: invalid %s parameters: %w", c.name, err)
+		}
+		key := pbkdf2.Key(password, kdf.Salt, kdf.Iterations, c.keyLen, prf.new)
+		block, err := c.new(key)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(iv) != block.BlockSize() {
+			return nil, nil, fmt.Errorf("pkcs12: invalid %s IV length", c.name)
+		}
+		plain, err := decryptCBC(block, iv, data)
+		if err != nil {
+			return nil, nil, err
+		}
+		return plain, &Encryption{
+			Algorithm:  fmt.Sprintf("PBES2 (PBKDF2 %s, %s)", prf.name, c.name),
+			Iterations: kdf.Iterations,
+		}, nil
+	}
+
+	return nil, nil, fmt.Errorf("pkcs12: unsupported PBES2 encryption scheme %s", params.EncryptionScheme.Algorithm)
+}
+
+// decryptCBC decrypts data and removes the PKCS#7 padding. Invalid padding
+// almost always means the password was wrong.
+func decryptCBC(block cipher.Block, iv, data []byte) ([]byte, error) {
+	bs := block.BlockSize()
+	if len(data) == 0 || len(data)%bs != 0 {
+		return nil, errors.New("pkcs12: encrypted data is not a multiple of the block size")
+	}
+	plain := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, data)
+
+	n := int(plain[len(plain)-1])
+	if n == 0 || n > bs || subtle.ConstantTimeCompare(plain[len(plain)-n:], bytes.Repeat([]byte{byte(n)}, n)) != 1 {
+		return nil, ErrIncorrectPassword
+	}
+	return plain[:len(plain)-n], nil
+}
+
+// pbkdf is the PKCS#12 key derivation function of RFC 7292 appendix B.2.
+// Like RC2, x/crypto does not export it.
+func pbkdf(d digest, salt, password []byte, iterations int, id byte, size int) []byte {
+	v := d.blockSize
+	u := d.new().Size()
+
+	fill := func(b []byte) []byte {
+		if len(b) == 0 {
+			return nil
+		}
+		out := make([]byte, v*((len(b)+v-1)/v))
+		for i := range out {
+			out[i] = b[i%len(b)]
+		}
+		return out
+	}
+	in := append(fill(salt), fill(password)...)
+	diversifier := bytes.Repeat([]byte{id}, v)
+
+	var out []byte
+	for len(out) < size {
+		h := d.new()
+		h.Write(diversifier)
+		h.Write(in)
+		a := h.Sum(nil)
+		for i := 1; i < iterations; i++ {
+			h.Reset()
+			h.Write(a)
+			a = h.Sum(a[:0])
+		}
+		out = append(out, a...)
+
+		if len(out) >= size {
+			break
+		}
+
+		// I_j = (I_j + B + 1) mod 2^(8v) for every v-byte block of I.
+		b := make([]byte, v)
+		for i := range b {
+			b[i] = a[i%u]
+		}
+		for j := 0; j < len(in); j += v {
+			carry := 1
+			for k := v - 1; k >= 0; k-- {
+				sum := int(in[j+k]) + int(b[k]) + carry
+				in[j+k] = byte(sum)
+				carry = sum >> 8
+			}
+		}
+	}
+	return out[:size]
+}
@@ -0,0 +1,359 @@
+// Package pkcs12 decodes PKCS#12 (PFX/P12) key stores as described in
+// RFC 7292. Unlike most decoders it keeps the bag structure and metadata
+// (friendly names, local key IDs, MAC and encryption parameters) so that
+// stores can be inspected, not just unpacked.
+package pkcs12
+
+import (
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"unicode/utf16"
+)
+
+// Bag types.
+const (
+	KeyBag         = "keyBag"
+	ShroudedKeyBag = "pkcs8ShroudedKeyBag"
+	CertBag        = "certBag"
+	CRLBag         = "crlBag"
+	SecretBag      = "secretBag"
+)
+
+// ErrIncorrectPassword is returned when the MAC or decryption shows that the
+// password is wrong.
+var ErrIncorrectPassword = errors.New("pkcs12: incorrect password")
+
+// Store is a decoded PKCS#12 file.
+type Store struct {
+	// MAC describes the integrity protection, nil if the file has none.
+	MAC  *MAC
+	Bags []Bag
+}
+
+// MAC describes the password-based integrity protection of a store.
+type MAC struct {
+	Algorithm  string
+	Iterations int
+	SaltLength int
+}
+
+// Encryption describes the password-based encryption protecting a bag.
+type Encryption struct {
+	Algorithm  string
+	Iterations int
+}
+
+// Bag is a single SafeBag of a store.
+type Bag struct {
+	Type         string
+	FriendlyName string
+	LocalKeyID   []byte
+	// Encryption is the algorithm protecting the bag, or the SafeContents
+	// it was stored in. Nil means the bag was stored in plain text.
+	Encryption *Encryption
+	// Certificate is set for certBags holding an X.509 certificate.
+	Certificate *x509.Certificate
+	// PrivateKey is set for keyBags and pkcs8ShroudedKeyBags.
+	PrivateKey crypto.PrivateKey
+}
+
+var (
+	oidDataContentType          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidEncryptedDataContentType = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 6}
+
+	oidFriendlyName = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 20}
+	oidLocalKeyID   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 21}
+
+	oidCertTypeX509 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 22, 1}
+
+	oidKeyBag          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 1}
+	oidShroudedKeyBag  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 2}
+	oidCertBag         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 3}
+	oidCRLBag          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 4}
+	oidSecretBag       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 5}
+	oidSafeContentsBag = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 6}
+)
+
+type pfxPdu struct {
+	Version  int
+	AuthSafe contentInfo
+	MacData  macData `asn1:"optional"`
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"tag:0,explicit,optional"`
+}
+
+type encryptedData struct {
+	Version              int
+	EncryptedContentInfo encryptedContentInfo
+}
+
+type encryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedContent           []byte `asn1:"tag:0,optional"`
+}
+
+type macData struct {
+	Mac        digestInfo
+	MacSalt    []byte
+	Iterations int `asn1:"optional,default:1"`
+}
+
+type digestInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	Digest    []byte
+}
+
+type safeBag struct {
+	ID         asn1.ObjectIdentifier
+	Value      asn1.RawValue     `asn1:"tag:0,explicit"`
+	Attributes []pkcs12Attribute `asn1:"set,optional"`
+}
+
+type pkcs12Attribute struct {
+	ID    asn1.ObjectIdentifier
+	Value asn1.RawValue `asn1:"set"`
+}
+
+type certBag struct {
+	ID   asn1.ObjectIdentifier
+	Data []byte `asn1:"tag:0,explicit"`
+}
+
+type encryptedPrivateKeyInfo struct {
+	AlgorithmIdentifier pkix.AlgorithmIdentifier
+	EncryptedData       []byte
+}
+
+type privateKeyInfo struct {
+	Version    int
+	Algorithm  pkix.AlgorithmIdentifier
+	PrivateKey []byte
+}
+
+// Decode parses a DER-encoded PKCS#12 store, verifies its MAC and decrypts
+// all bags with password.
+func Decode(data []byte, password string) (*Store, error) {
+	var pfx pfxPdu
+	if err := unmarshal(data, &pfx); err != nil {
+		return nil, fmt.Errorf("pkcs12: invalid PFX: %w", err)
+	}
+	if pfx.Version != 3 {
+		return nil, fmt.Errorf("pkcs12: unsupported version %d", pfx.Version)
+	}
+	if !pfx.AuthSafe.ContentType.Equal(oidDataContentType) {
+		return nil, errors.New("pkcs12: only password-protected stores are supported")
+	}
+
+	var authSafe []byte
+	if err := unmarshal(pfx.AuthSafe.Content.Bytes, &authSafe); err != nil {
+		return nil, fmt.Errorf("pkcs12: invalid authenticated safe: %w", err)
+	}
+
+	store := &Store{}
+	bmpPassword := bmpString(password)
+	if len(pfx.MacData.Mac.Algorithm.Algorithm) > 0 {
+		mac, usedPassword, err := verifyMAC(&pfx.MacData, authSafe, bmpPassword)
+		if err != nil {
+			return nil, err
+		}
+		store.MAC = mac
+		bmpPassword = usedPassword
+	}
+
+	var contents []contentInfo
+	if err := unmarshal(authSafe, &contents); err != nil {
+		return nil, fmt.Errorf("pkcs12: invalid authenticated safe: %w", err)
+	}
+
+	for _, ci := range contents {
+		var safeContents []byte
+		var enc *Encryption
+		switch {
+		case ci.ContentType.Equal(oidDataContentType):
+			if err := unmarshal(ci.Content.Bytes, &safeContents); err != nil {
+				return nil, fmt.Errorf("pkcs12: invalid safe contents: %w", err)
+			}
+		case ci.ContentType.Equal(oidEncryptedDataContentType):
+			var ed encryptedData
+			if err := unmarshal(ci.Content.Bytes, &ed); err != nil {
+				return nil, fmt.Errorf("pkcs12: invalid encrypted data: %w", err)
+			}
+			var err error
+			alg := ed.EncryptedContentInfo.ContentEncryptionAlgorithm
+			safeContents, enc, err = decrypt(alg, ed.EncryptedContentInfo.EncryptedContent, password, bmpPassword)
+			if err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("pkcs12: unsupported content type %s", ci.ContentType)
+		}
+
+		bags, err := decodeSafeContents(safeContents, enc, password, bmpPassword)
+		if err != nil {
+			return nil, err
+		}
+		store.Bags = append(store.Bags, bags...)
+	}
+
+	return store, nil
+}
+
+func decodeSafeContents(data []byte, enc *Encryption, password string, bmpPassword []byte) ([]Bag, error) {
+	var safeBags []safeBag
+	if err := unmarshal(data, &safeBags); err != nil {
+		return nil, fmt.Errorf("pkcs12: invalid safe contents: %w", err)
+	}
+
+	var bags []Bag
+	for _, sb := range safeBags {
+		bag := Bag{Encryption: enc}
+		if err := decodeAttributes(&bag, sb.Attributes); err != nil {
+			return nil, err
+		}
+
+		switch {
+		case sb.ID.Equal(oidKeyBag):
+			bag.Type = KeyBag
+			key, err := x509.ParsePKCS8PrivateKey(sb.Value.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("pkcs12: invalid private key: %w", err)
+			}
+			bag.PrivateKey = key
+		case sb.ID.Equal(oidShroudedKeyBag):
+			bag.Type = ShroudedKeyBag
+			key, keyEnc, err := decryptPrivateKey(sb.Value.Bytes, password, bmpPassword)
+			if err != nil {
+				return nil, err
+			}
+			bag.PrivateKey = key
+			bag.Encryption = keyEnc
+		case sb.ID.Equal(oidCertBag):
+			bag.Type = CertBag
+			var cb certBag
+			if err := unmarshal(sb.Value.Bytes, &cb); err != nil {
+				return nil, fmt.Errorf("pkcs12: invalid certificate bag: %w", err)
+			}
+			if cb.ID.Equal(oidCertTypeX509) {
+				cert, err := x509.ParseCertificate(cb.Data)
+				if err != nil {
+					return nil, fmt.Errorf("pkcs12: invalid certificate: %w", err)
+				}
+				bag.Certificate = cert
+			}
+		case sb.ID.Equal(oidCRLBag):
+			bag.Type = CRLBag
+		case sb.ID.Equal(oidSecretBag):
+			bag.Type = SecretBag
+		case sb.ID.Equal(oidSafeContentsBag):
+			nested, err := decodeSafeContents(sb.Value.FullBytes, enc, password, bmpPassword)
+			if err != nil {
+				return nil, err
+			}
+			bags = append(bags, nested...)
+			continue
+		default:
+			return nil, fmt.Errorf("pkcs12: unsupported bag type %s", sb.ID)
+		}
+
+		bags = append(bags, bag)
+	}
+	return bags, nil
+}
+
+// DecryptPrivateKey decrypts a PKCS#8 EncryptedPrivateKeyInfo, as found in
+// ENCRYPTED PRIVATE KEY PEM blocks. Both PBES2 and the PKCS#12 password-based
+// encryption schemes are supported.
+func DecryptPrivateKey(der []byte, password string) (crypto.PrivateKey, *Encryption, error) {
+	return decryptPrivateKey(der, password, bmpString(password))
+}
+
+func decryptPrivateKey(der []byte, password string, bmpPassword []byte) (crypto.PrivateKey, *Encryption, error) {
+	var epki encryptedPrivateKeyInfo
+	if err := unmarshal(der, &epki); err != nil {
+		return nil, nil, fmt.Errorf("pkcs12: invalid encrypted private key: %w", err)
+	}
+	plain, enc, err := decrypt(epki.AlgorithmIdentifier, epki.EncryptedData, password, bmpPassword)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(plain)
+	if err != nil {
+		// A wrong password occasionally yields valid padding, but not a
+		// PrivateKeyInfo structure.
+		var info privateKeyInfo
+		if unmarshal(plain, &info) != nil {
+			return nil, nil, ErrIncorrectPassword
+		}
+		return nil, nil, fmt.Errorf("pkcs12: invalid private key: %w", err)
+	}
+	return key, enc, nil
+}
+
+func decodeAttributes(bag *Bag, attrs []pkcs12Attribute) error {
+	for _, attr := range attrs {
+		switch {
+		case attr.ID.Equal(oidFriendlyName):
+			var raw asn1.RawValue
+			if err := unmarshal(attr.Value.Bytes, &raw); err != nil {
+				return fmt.Errorf("pkcs12: invalid friendly name: %w", err)
+			}
+			name, err := decodeBMPString(raw.Bytes)
+			if err != nil {
+				return err
+			}
+			bag.FriendlyName = name
+		case attr.ID.Equal(oidLocalKeyID):
+			var id []byte
+			if err := unmarshal(attr.Value.Bytes, &id); err != nil {
+				return fmt.Errorf("pkcs12: invalid local key ID: %w", err)
+			}
+			bag.LocalKeyID = id
+		}
+	}
+	return nil
+}
+
+// unmarshal is asn1.Unmarshal that rejects trailing data.
+func unmarshal(in []byte, out any) error {
+	rest, err := asn1.Unmarshal(in, out)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 0 {
+		return errors.New("trailing data")
+	}
+	return nil
+}
+
+// bmpString encodes s as a NUL-terminated UTF-16BE string, the password
+// format used by the PKCS#12 key derivation function.
+func bmpString(s string) []byte {
+	encoded := utf16.Encode([]rune(s))
+	out := make([]byte, 0, 2*len(encoded)+2)
+	for _, c := range encoded {
+		out = append(out, byte(c>>8), byte(c))
+	}
+	return append(out, 0, 0)
+}
+
+func decodeBMPString(b []byte) (string, error) {
+	if len(b)%2 != 0 {
+		return "", errors.New("pkcs12: odd-length BMP string")
+	}
+	s := make([]uint16, 0, len(b)/2)
+	for i := 0; i < len(b); i += 2 {
+		s = append(s, uint16(b[i])<<8|uint16(b[i+1]))
+	}
+	if len(s) > 0 && s[len(s)-1] == 0 {
+		s = s[:len(s)-1]
+	}
+	return string(utf16.Decode(s)), nil
+}
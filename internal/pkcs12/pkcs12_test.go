@@ -0,0 +1,298 @@
+package pkcs12
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Test stores were created with OpenSSL 3 from a self-signed P-256
+// certificate for example.com:
+//
+//	openssl pkcs12 -export -in c.pem -inkey k.pem -name "my cert" -passout pass:secret
+//	openssl pkcs12 -export -legacy -in c.pem -inkey k.pem -name "my cert" -passout pass:secret
+//	openssl pkcs12 -export -nokeys -in c.pem -passout pass:
+
+const aesStore = "" +
+	"MIIESwIBAzCCBAEGCSqGSIb3DQEHAaCCA/IEggPuMIID6jCCAoIGCSqGSIb3DQEHBqCCAnMwggJv" +
+	"AgEAMIICaAYJKoZIhvcNAQcBMFcGCSqGSIb3DQEFDTBKMCkGCSqGSIb3DQEFDDAcBAh9awrgW/Hg" +
+	"LgICCAAwDAYIKoZIhvcNAgkFADAdBglghkgBZQMEASoEEEEVA6J5gsmOjhq8mJlrO42AggIACj68" +
+	"VaA1W9gM0iKOpJtLQDT5rqOatbOmwrHCMwpC++M9bjugkTF7+WdQMCOR6Z0rJ0aPD/cp3uPBl+KR" +
+	"VrTgLL1Auc9vLsge7QJYwnM4++yJFxLb/aX95Wk7fls0/NwC0S9PrxJEilTZwO55dQr+xSGXnGBc" +
+	"gMrJwAkIcsGkE+MmXthbBpstZDnsmKgvoM5INV+kh4afrI2PpjdcxIqv9DXX5B34BtEx+W45OxG3" +
+	"ZlcxjxiyjxxPL48FTjaDsUP2zYMy27XFQrkglns/YXAkT2wsznTsrN/WxFWpRN2eV6DJOuykVc8G" +
+	"MZBcBu7l/UlBXJuj5CySRmgv6DHflyXxC+ghprIv4Nl2hmdTJo5tLDn2/IWpz7WIKwBnf6B5ktEJ" +
+	"8gue/2CtUU3uBYS0NUCEipIdL+POnvyQikJyYq7hZXE0oheuarkx2urNa0CtkSS/0JYBvn3zi7r3" +
+	"GvsqGxmc/PTf0ipsoJHZeyvm0PkKf4ukh1WpGSjCNyFcPQqX7eBT3GGOrORoHK1s8b2pCI9fBRF4" +
+	"zAdCOsw6+LcXTWPXxYqzG62rFvqM04c/S/Ng1lwaFkbL7wY7+GPbNSY8O+g7tGdTLZI1+KXE/PKK" +
+	"VJHftY9Srxr/DdRId4ZWEW0rl1l4cqvlnYnSkmsQmJbQyhgr8aFmIr8EF5HLesrT2YnJiMowggFg" +
+	"BgkqhkiG9w0BBwGgggFRBIIBTTCCAUkwggFFBgsqhkiG9w0BDAoBAqCB7zCB7DBXBgkqhkiG9w0B" +
+	"BQ0wSjApBgkqhkiG9w0BBQwwHAQICueiuWmgnGQCAggAMAwGCCqGSIb3DQIJBQAwHQYJYIZIAWUD" +
+	"BAEqBBBwWM24c9Hz9EcMhK3X2xFxBIGQeEgE8au9fQO5DVuf6UBqenI/2XoB1yW/gRCJv+8mqgKG" +
+	"vA2yfjreC/bD4AVdPpwvyuWrABmYeF9XvgY7ITHmfrSb5fFDsYT3O8ghFWm41Ga/J1TW318gsN+1" +
+	"AHtoEXGwil7trahtJebRkNZewlaBAblpM+iKwU7ytDSU8C0K8fKsvtV1YZyTYdCCgv8+nBhwMUQw" +
+	"HQYJKoZIhvcNAQkUMRAeDgBtAHkAIABjAGUAcgB0MCMGCSqGSIb3DQEJFTEWBBRDZfdRRMgEEGqI" +
+	"OR9bGsK7mtRAQjBBMDEwDQYJYIZIAWUDBAIBBQAEIJMrZmL/udXhwV+Mn/1g+bjcRVJQaErUzYKu" +
+	"qSeHXq18BAhfLjZ9Sj/e2QICCAA="
+
+const legacyStore = "" +
+	"MIIDxQIBAzCCA4sGCSqGSIb3DQEHAaCCA3wEggN4MIIDdDCCAkcGCSqGSIb3DQEHBqCCAjgwggI0" +
+	"AgEAMIICLQYJKoZIhvcNAQcBMBwGCiqGSIb3DQEMAQYwDgQIfZYXD88fYy4CAggAgIICANb6LFFM" +
+	"cHZILMuLh32NSa4MwHMvukvnyVEauQsjQ64Rj6dtYrLRMGsx0KLMh5lSJrL2vsXeYIJBGsfrML1Z" +
+	"LibRCYw6NWHevR4R9yTVaIspQvTGLZrbeiJKrPXkMh5uMrp5at862Ol0JAu/m4BYbHOm/gTrRoYI" +
+	"rmdGKA4zTZ5bimCkq48mzMZx08VUjchGAM7B8Qg1iotD78xqrVhAL8ElZKFwrvDwgpHBdBxsr83/" +
+	"7dD9ahHaNxiV9lAQFb/9jChtnFqDpN87MnyG0KdyJ1Kf9+P4OvwYTClF4dEZRc5GySprlegfJoCX" +
+	"LMU1JmbBtDaywdJoJUPlgp4U6e6LXtffTZyihrmQzk7sxn0/UERcZXpNAt9W2Wg2uGSzRywNT5Hu" +
+	"S580g57IZnH1jXMvXoaVqwX3L+oiwW2buyDYvgLUHNs7EiLQei/0oSTQe1h+EbUkaRCgDKXYeL1P" +
+	"55E0qHBQt9j3dO5UIF2avdYM0Mj4NjVFw+WFHEfZIWwNIfr57X8GuAuuRyJK0ei5SM81+FUqmAqG" +
+	"TNXJAleANam2BaopWxTkio9Z2oTu58UPtISkQjowemSueoNP4sP2W3JVjiNvdbnpz4r1ZVqhM9dc" +
+	"woWWSCT/S91V01kmGrYozWLCaC2sGELpBBBPl0XgXNg/rAhaGFMwzh+u5HnoBwO3M8frMIIBJQYJ" +
+	"KoZIhvcNAQcBoIIBFgSCARIwggEOMIIBCgYLKoZIhvcNAQwKAQKggbQwgbEwHAYKKoZIhvcNAQwB" +
+	"AzAOBAhXEOqKI6ow6AICCAAEgZCIWN5ROiDAORAlvU6oj1QQmYLuWwORaptUD5BrNWHcu458x1By" +
+	"Ujy/l5kg98T9GwUtBU2uf8IMbG1Oc5Nr2r/9JZMIxqHYkY5MB6pOGlJegmBipNaJFquLQTiKVQaa" +
+	"B1d5+Dsm1yvpUrhcTlEyQx7Ej+7tPzDAABG2L+Sk/kXf1DvH8yBEBWsrNPTjYzm3fI8xRDAdBgkq" +
+	"hkiG9w0BCRQxEB4OAG0AeQAgAGMAZQByAHQwIwYJKoZIhvcNAQkVMRYEFENl91FEyAQQaog5H1sa" +
+	"wrua1EBCMDEwITAJBgUrDgMCGgUABBT2eKPs6hoxHDYIO2NqIIi3bYf9QQQIQWjBzRpjk78CAggA"
+
+const emptyPasswordStore = "" +
+	"MIICpwIBAzCCAl0GCSqGSIb3DQEHAaCCAk4EggJKMIICRjCCAkIGCSqGSIb3DQEHBqCCAjMwggIv" +
+	"AgEAMIICKAYJKoZIhvcNAQcBMFcGCSqGSIb3DQEFDTBKMCkGCSqGSIb3DQEFDDAcBAgOTWvLNGIi" +
+	"NgICCAAwDAYIKoZIhvcNAgkFADAdBglghkgBZQMEASoEEJUk+YDg9X/D3zB2CeF+pyiAggHAufno" +
+	"7+iW8mEZzaOBhQvEppCBMpkJ7i7rmFYBEkHKcDOvN37YiMO0c1O1hWBB4xClvKXGM9J74EJLns5t" +
+	"7YOT9QOZPUexlVn2mGpVZk1gxZY1RFf+nkdZb1ds2ymnWTjKhAi8QjcYV/K+Mge1n05f4GF2pROY" +
+	"41Aq9dIel2spNRayuYZdk51LIsU6JgtSshGXBenKphtnzuNNBuTAdMOWDtxe7TLAakexxv3Qbv+6" +
+	"FU1VNXH2USTSl768L6t5wI7N+n1LHZvVmFSqCjUDuZCPK8uBlGBmSChJS6rh0NCqkzH0DIBjku9t" +
+	"hRoueQeyy3oE9yi8hdYaZ3Dy1Ke+CUCi/qY4ysWVY1u9v5iz6MyXD0K7m3aXbAxT5fT0ddSyTcj1" +
+	"djoeElJ9j+YaGqj6SLjLCE/z1ac4zRiNed+iQBqiJNZefNK8eGzqqlYhGK8tVgp+pxsY20g2AMIW" +
+	"IBMHy+4SAsd0nIwqecOBUo7sV8J0/EN8+KkvJ8gLMiPIoPDd/sUGHGlswlN3quN4S/INCsdJfzi+" +
+	"wjZ7fjpfN7fU7zSaGRynSLsPmu1B19ZZJJ1tvxyqx91xn83bdDYwKNNePwo96zBBMDEwDQYJYIZI" +
+	"AWUDBAIBBQAEIAsYdU/XuyXkM2qGM6lRnR2Gnzb0D9vkZUPyMzmOI31qBAioGtOnUxmVWAICCAA="
+
+// ed448Key is an encrypted PKCS#8 Ed448 key, which Go does not support:
+//
+//	openssl genpkey -algorithm ed448 | openssl pkcs8 -topk8 -v2 aes-256-cbc -passout pass:secret -outform DER
+const ed448Key = "" +
+	"MIGrMFcGCSqGSIb3DQEFDTBKMCkGCSqGSIb3DQEFDDAcBAiy8qmRJ5lz7QICCAAwDAYIKoZIhvcN" +
+	"AgkFADAdBglghkgBZQMEASoEEOaN/hE+PutAwbMDjRg5LWkEUDwgbgrE1WqTo7saoWG6z26HAo9S" +
+	"7lkMU7rEbgUY5c8qK/2RmdzoYO6j6gqF+6KYxlaPbYC12m/cGtIxVNDTX5mfaeP4MYwl7pGAG8Yy" +
+	"Ppk4"
+
+func decodeFixture(t *testing.T, s string) []byte {
+	t.Helper()
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		t.Fatalf("invalid fixture: %v", err)
+	}
+	return data
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name         string
+		store        string
+		password     string
+		wantMAC      string
+		wantCertEnc  string
+		wantKeyEnc   string
+		wantKeyBag   bool
+		wantFriendly string
+	}{
+		{
+			name:         "PBES2",
+			store:        aesStore,
+			password:     "secret",
+			wantMAC:      "SHA-256",
+			wantCertEnc:  "PBES2 (PBKDF2 HMAC-SHA256, AES-256-CBC)",
+			wantKeyEnc:   "PBES2 (PBKDF2 HMAC-SHA256, AES-256-CBC)",
+			wantKeyBag:   true,
+			wantFriendly: "my cert",
+		},
+		{
+			name:         "legacy",
+			store:        legacyStore,
+			password:     "secret",
+			wantMAC:      "SHA-1",
+			wantCertEnc:  "pbeWithSHAAnd40BitRC2-CBC",
+			wantKeyEnc:   "pbeWithSHAAnd3-KeyTripleDES-CBC",
+			wantKeyBag:   true,
+			wantFriendly: "my cert",
+		},
+		{
+			name:        "empty password",
+			store:       emptyPasswordStore,
+			wantMAC:     "SHA-256",
+			wantCertEnc: "PBES2 (PBKDF2 HMAC-SHA256, AES-256-CBC)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := Decode(decodeFixture(t, tt.store), tt.password)
+			if err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+			if store.MAC == nil || store.MAC.Algorithm != tt.wantMAC || store.MAC.Iterations != 2048 {
+				t.Errorf("unexpected MAC %+v, want %s with 2048 iterations", store.MAC, tt.wantMAC)
+			}
+
+			var cert, key *Bag
+			for i := range store.Bags {
+				switch store.Bags[i].Type {
+				case CertBag:
+					cert = &store.Bags[i]
+				case ShroudedKeyBag:
+					key = &store.Bags[i]
+				}
+			}
+
+			if cert == nil || cert.Certificate == nil {
+				t.Fatal("expected a certificate bag")
+			}
+			if cn := cert.Certificate.Subject.CommonName; cn != "example.com" {
+				t.Errorf("expected CN example.com, got %q", cn)
+			}
+			if cert.Encryption == nil || cert.Encryption.Algorithm != tt.wantCertEnc {
+				t.Errorf("certificate encryption = %+v, want %s", cert.Encryption, tt.wantCertEnc)
+			}
+			if cert.FriendlyName != tt.wantFriendly {
+				t.Errorf("friendly name = %q, want %q", cert.FriendlyName, tt.wantFriendly)
+			}
+
+			if !tt.wantKeyBag {
+				if key != nil {
+					t.Error("unexpected key bag")
+				}
+				return
+			}
+			if key == nil {
+				t.Fatal("expected a shrouded key bag")
+			}
+			if key.Encryption == nil || key.Encryption.Algorithm != tt.wantKeyEnc {
+				t.Errorf("key encryption = %+v, want %s", key.Encryption, tt.wantKeyEnc)
+			}
+			if len(key.LocalKeyID) == 0 || !bytes.Equal(key.LocalKeyID, cert.LocalKeyID) {
+				t.Errorf("local key IDs do not match: %x, %x", key.LocalKeyID, cert.LocalKeyID)
+			}
+			priv, ok := key.PrivateKey.(*ecdsa.PrivateKey)
+			if !ok {
+				t.Fatalf("expected ECDSA key, got %T", key.PrivateKey)
+			}
+			if !priv.PublicKey.Equal(cert.Certificate.PublicKey) {
+				t.Error("private key does not match certificate")
+			}
+		})
+	}
+}
+
+func TestDecode_IncorrectPassword(t *testing.T) {
+	for _, store := range []string{aesStore, legacyStore} {
+		_, err := Decode(decodeFixture(t, store), "wrong")
+		if !errors.Is(err, ErrIncorrectPassword) {
+			t.Errorf("expected ErrIncorrectPassword, got %v", err)
+		}
+	}
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	if _, err := Decode([]byte("not a pfx"), ""); err == nil {
+		t.Error("expected error for invalid input")
+	}
+}
+
+func TestDecryptPrivateKey_Unsupported(t *testing.T) {
+	der := decodeFixture(t, ed448Key)
+	_, _, err := DecryptPrivateKey(der, "secret")
+	if err == nil || errors.Is(err, ErrIncorrectPassword) || !strings.Contains(err.Error(), "invalid private key") {
+		t.Errorf("expected invalid private key error, got %v", err)
+	}
+	if _, _, err := DecryptPrivateKey(der, "wrong"); !errors.Is(err, ErrIncorrectPassword) {
+		t.Errorf("expected ErrIncorrectPassword, got %v", err)
+	}
+}
+
+func TestDecode_IterationLimit(t *testing.T) {
+	authSafe, err := asn1.Marshal([]contentInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := asn1.Marshal(authSafe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pfx, err := asn1.Marshal(pfxPdu{
+		Version: 3,
+		AuthSafe: contentInfo{
+			ContentType: oidDataContentType,
+			Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: content},
+		},
+		MacData: macData{
+			Mac:        digestInfo{Algorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA1}, Digest: make([]byte, 20)},
+			MacSalt:    []byte("salt"),
+			Iterations: 1 << 30,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Decode(pfx, "secret"); err == nil || !strings.Contains(err.Error(), "iteration count") {
+		t.Errorf("expected iteration count error, got %v", err)
+	}
+
+	params, err := asn1.Marshal(pbkdf2Params{Salt: []byte("salt"), Iterations: 1 << 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pbes2, err := asn1.Marshal(pbes2Params{
+		KeyDerivationFunc: pkix.AlgorithmIdentifier{Algorithm: oidPBKDF2, Parameters: asn1.RawValue{FullBytes: params}},
+		EncryptionScheme:  pkix.AlgorithmIdentifier{Algorithm: oidAES256CBC},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	alg := pkix.AlgorithmIdentifier{Algorithm: oidPBES2, Parameters: asn1.RawValue{FullBytes: pbes2}}
+	if _, _, err := decrypt(alg, make([]byte, 16), "secret", nil); err == nil || !strings.Contains(err.Error(), "iteration count") {
+		t.Errorf("expected iteration count error, got %v", err)
+	}
+}
+
+// TestRC2 uses the test vectors of RFC 2268 section 5.
+func TestRC2(t *testing.T) {
+	tests := []struct {
+		key, plain, cipher string
+		bits               int
+	}{
+		{"0000000000000000", "0000000000000000", "ebb773f993278eff", 63},
+		{"ffffffffffffffff", "ffffffffffffffff", "278b27e42e2f0d49", 64},
+		{"3000000000000000", "1000000000000001", "30649edf9be7d2c2", 64},
+		{"88", "0000000000000000", "61a8a244adacccf0", 64},
+		{"88bca90e90875a", "0000000000000000", "6ccf4308974c267f", 64},
+		{"88bca90e90875a7f0f79c384627bafb2", "0000000000000000", "1a807d272bbe5db1", 64},
+		{"88bca90e90875a7f0f79c384627bafb2", "0000000000000000", "2269552ab0f85ca6", 128},
+	}
+
+	for _, tt := range tests {
+		key, _ := hex.DecodeString(tt.key)
+		plain, _ := hex.DecodeString(tt.plain)
+		want, _ := hex.DecodeString(tt.cipher)
+
+		block, err := newRC2Cipher(key, tt.bits)
+		if err != nil {
+			t.Fatalf("newRC2Cipher failed: %v", err)
+		}
+		got := make([]byte, rc2BlockSize)
+		block.Encrypt(got, plain)
+		if !bytes.Equal(got, want) {
+			t.Errorf("key %s: Encrypt = %x, want %x", tt.key, got, want)
+		}
+		block.Decrypt(got, got)
+		if !bytes.Equal(got, plain) {
+			t.Errorf("key %s: Decrypt = %x, want %x", tt.key, got, plain)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package tlsquery
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Certificate sources.
+const (
+	SourceServed  = "served"
+	SourceFile    = "file"
+	SourceFetched = "fetched"
+)
+
+// maxAIAFetches bounds the number of issuers fetched for one chain.
+const maxAIAFetches = 5
+
+// CompleteChain fetches missing intermediates when chain does not build to a
+// trusted root. Starting from the top of the chain, it follows the AIA
+// caIssuers URLs (DER or PKCS#7) and appends each fetched issuer with Source
+// "fetched". Download failures are recorded in the AIAError of the
+// certificate whose URL failed. A nil roots pool uses the system roots and a
+// nil client uses http.DefaultClient.
+func CompleteChain(ctx context.Context, chain *ChainInfo, client *http.Client, roots *x509.CertPool) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	for fetches := 0; fetches < maxAIAFetches && len(chain.certs) > 0; fetches++ {
+		if !missingIssuer(chain.certs, roots) {
+			return
+		}
+
+		top := topOfChain(chain.certs)
+		cert := chain.certs[top]
+		if len(cert.IssuingCertificateURL) == 0 {
+			return
+		}
+
+		issuer, err := fetchIssuer(ctx, client, cert)
+		if err != nil {
+			chain.Certificates[top].AIAError = err.Error()
+			return
+		}
+
+		info := CertInfoFromCert(issuer)
+		info.Source = SourceFetched
+		chain.Certificates = append(chain.Certificates, info)
+		chain.certs = append(chain.certs, issuer)
+	}
+}
+
+// missingIssuer reports whether certs fail to build to a root in roots
+// because an issuer is unknown.
+func missingIssuer(certs []*x509.Certificate, roots *x509.CertPool) bool {
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	var unknownAuthority x509.UnknownAuthorityError
+	return errors.As(err, &unknownAuthority)
+}
+
+// topOfChain follows the issuers of the leaf within certs and returns the
+// index of the last certificate reached.
+func topOfChain(certs []*x509.Certificate) int {
+	top := 0
+	for visited := 1; visited < len(certs); visited++ {
+		issuer := findIssuer(certs[top], certs)
+		if issuer == nil {
+			break
+		}
+		for i, cert := range certs {
+			if cert == issuer {
+				top = i
+			}
+		}
+	}
+	return top
+}
+
+// fetchIssuer downloads the caIssuers URLs of cert in order and returns the
+// first certificate that signed cert.
+func fetchIssuer(ctx context.Context, client *http.Client, cert *x509.Certificate) (*x509.Certificate, error) {
+	var errs []error
+	for _, url := range cert.IssuingCertificateURL {
+		candidates, err := fetchCertificates(ctx, client, url)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, candidate := range candidates {
+			if cert.CheckSignatureFrom(candidate) == nil {
+				return candidate, nil
+			}
+		}
+		errs = append(errs, fmt.Errorf("no certificate from %s signed %s", url, cert.Subject))
+	}
+	return nil, errors.Join(errs...)
+}
+
+func fetchCertificates(ctx context.Context, client *http.Client, url string) ([]*x509.Certificate, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid AIA URL: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("AIA download failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("AIA download from %s returned HTTP %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRevocationResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read AIA response: %w", err)
+	}
+
+	certs, err := parseIssuerCertificates(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid AIA response from %s: %w", url, err)
+	}
+	return certs, nil
+}
+
+// parseIssuerCertificates accepts the encodings found at caIssuers URLs:
+// a DER certificate, a DER PKCS#7 bundle, or (non-conforming) PEM.
+func parseIssuerCertificates(data []byte) ([]*x509.Certificate, error) {
+	if block, _ := pem.Decode(data); block != nil {
+		data = block.Bytes
+	}
+	if cert, err := x509.ParseCertificate(data); err == nil {
+		return []*x509.Certificate{cert}, nil
+	}
+	return parsePKCS7Certificates(data)
+}
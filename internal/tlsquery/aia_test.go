@@ -0,0 +1,176 @@
+package tlsquery
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// aiaServer serves issuer certificates by path and counts requests.
+type aiaServer struct {
+	*httptest.Server
+	files    map[string][]byte
+	requests int32
+}
+
+func startAIAServer(t *testing.T) *aiaServer {
+	t.Helper()
+	s := &aiaServer{files: map[string][]byte{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&s.requests, 1)
+		data, ok := s.files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// aiaChain is root -> intermediate -> issuing CA -> leaf, where each
+// certificate below the root points to its issuer at the AIA server.
+type aiaChain struct {
+	root, intermediate, issuing, leaf *testCert
+	roots                             *x509.CertPool
+}
+
+func newAIAChain(t *testing.T, server *aiaServer) *aiaChain {
+	t.Helper()
+	c := &aiaChain{root: newTestCert(t, caTemplate("Test Root"), nil)}
+
+	tmpl := caTemplate("Test Intermediate")
+	tmpl.IssuingCertificateURL = []string{server.URL + "/root.crt"}
+	c.intermediate = newTestCert(t, tmpl, c.root)
+
+	tmpl = caTemplate("Test Issuing CA")
+	tmpl.IssuingCertificateURL = []string{server.URL + "/missing.crt", server.URL + "/intermediate.p7c"}
+	c.issuing = newTestCert(t, tmpl, c.intermediate)
+
+	tmpl = leafTemplate("localhost")
+	tmpl.IssuingCertificateURL = []string{server.URL + "/issuing.crt"}
+	c.leaf = newTestCert(t, tmpl, c.issuing)
+
+	server.files["/root.crt"] = c.root.cert.Raw
+	server.files["/intermediate.p7c"] = newPKCS7(t, c.intermediate)
+	server.files["/issuing.crt"] = []byte(encodePEM(c.issuing.cert.Raw))
+
+	c.roots = x509.NewCertPool()
+	c.roots.AddCert(c.root.cert)
+	return c
+}
+
+func chainOf(certs ...*testCert) *ChainInfo {
+	chain := &ChainInfo{}
+	for _, c := range certs {
+		chain.certs = append(chain.certs, c.cert)
+		info := CertInfoFromCert(c.cert)
+		info.Source = SourceServed
+		chain.Certificates = append(chain.Certificates, info)
+	}
+	return chain
+}
+
+func TestCompleteChain(t *testing.T) {
+	server := startAIAServer(t)
+	c := newAIAChain(t, server)
+
+	chain := chainOf(c.leaf)
+	CompleteChain(context.Background(), chain, nil, c.roots)
+
+	want := []struct{ subject, source string }{
+		{"CN=localhost", SourceServed},
+		{"CN=Test Issuing CA", SourceFetched},
+		{"CN=Test Intermediate", SourceFetched},
+	}
+	if len(chain.Certificates) != len(want) {
+		t.Fatalf("expected %d certificates, got %d", len(want), len(chain.Certificates))
+	}
+	for i, w := range want {
+		got := chain.Certificates[i]
+		if got.Subject != w.subject || got.Source != w.source {
+			t.Errorf("certificate %d: got %s (%s), want %s (%s)", i, got.Subject, got.Source, w.subject, w.source)
+		}
+		if got.AIAError != "" {
+			t.Errorf("certificate %d: unexpected AIA error %s", i, got.AIAError)
+		}
+	}
+	if v := VerifyChain(chain.certs, "localhost", c.roots); !v.Verified {
+		t.Errorf("completed chain does not verify: %s", v.Error)
+	}
+}
+
+func TestCompleteChain_Complete(t *testing.T) {
+	server := startAIAServer(t)
+	c := newAIAChain(t, server)
+
+	chain := chainOf(c.leaf, c.issuing, c.intermediate)
+	CompleteChain(context.Background(), chain, nil, c.roots)
+
+	if len(chain.Certificates) != 3 {
+		t.Errorf("expected chain to be unchanged, got %d certificates", len(chain.Certificates))
+	}
+	if server.requests != 0 {
+		t.Errorf("expected no AIA requests, got %d", server.requests)
+	}
+}
+
+func TestCompleteChain_Error(t *testing.T) {
+	server := startAIAServer(t)
+	c := newAIAChain(t, server)
+	delete(server.files, "/intermediate.p7c")
+
+	chain := chainOf(c.leaf, c.issuing)
+	CompleteChain(context.Background(), chain, nil, c.roots)
+
+	if len(chain.Certificates) != 2 {
+		t.Fatalf("expected no certificates to be added, got %d", len(chain.Certificates))
+	}
+	if err := chain.Certificates[1].AIAError; !strings.Contains(err, "404") {
+		t.Errorf("expected AIA error on the issuing CA, got %q", err)
+	}
+}
+
+func TestQueryContext_FetchIssuers(t *testing.T) {
+	server := startAIAServer(t)
+	c := newAIAChain(t, server)
+
+	tlsServer, addr := startTLSServerWithConfig(t, &tls.Config{
+		Certificates: []tls.Certificate{serverCertificate(c.leaf)},
+	})
+	defer tlsServer.Close()
+
+	opts := QueryOptions{ServerName: "localhost", RootCAs: c.roots}
+	chain, err := QueryContext(context.Background(), addr, opts)
+	if err != nil {
+		t.Fatalf("QueryContext failed: %v", err)
+	}
+	if len(chain.Certificates) != 1 || chain.Verification.Verified {
+		t.Errorf("expected incomplete, unverified chain without FetchIssuers")
+	}
+	if chain.CompletedVerification != nil {
+		t.Errorf("unexpected completed verification without FetchIssuers")
+	}
+
+	opts.FetchIssuers = true
+	chain, err = QueryContext(context.Background(), addr, opts)
+	if err != nil {
+		t.Fatalf("QueryContext failed: %v", err)
+	}
+	if len(chain.Certificates) != 3 {
+		t.Errorf("expected completed chain, got %d certificates", len(chain.Certificates))
+	}
+	// The served chain is still incomplete and must not verify.
+	if v := chain.Verification; v.Verified || !v.IncompleteChain || v.Reason != ReasonUnknownAuthority {
+		t.Errorf("expected served chain to fail as incomplete, got %+v", v)
+	}
+	if v := chain.CompletedVerification; v == nil || !v.Verified {
+		t.Errorf("expected completed chain to verify, got %+v", v)
+	}
+}
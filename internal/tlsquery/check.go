@@ -0,0 +1,109 @@
+package tlsquery
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CheckStatus is a Nagios-compatible plugin status. Its value is the exit code.
+type CheckStatus int
+
+// Nagios plugin statuses.
+const (
+	CheckOK CheckStatus = iota
+	CheckWarning
+	CheckCritical
+	CheckUnknown
+)
+
+func (s CheckStatus) String() string {
+	switch s {
+	case CheckOK:
+		return "OK"
+	case CheckWarning:
+		return "WARNING"
+	case CheckCritical:
+		return "CRITICAL"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// ExpiryCheck holds the result of checking a chain against expiry thresholds.
+type ExpiryCheck struct {
+	Status   CheckStatus
+	Summary  string
+	Perfdata []string
+}
+
+// String formats the result as a single Nagios plugin output line.
+func (c *ExpiryCheck) String() string {
+	line := fmt.Sprintf("TLS %s - %s", c.Status, c.Summary)
+	if len(c.Perfdata) > 0 {
+		line += " | " + strings.Join(c.Perfdata, " ")
+	}
+	return line
+}
+
+// CheckExpiry compares the NotAfter of every certificate in chain with now.
+// Certificates expiring within crit are CRITICAL, within warn WARNING.
+func CheckExpiry(chain *ChainInfo, warn, crit time.Duration, now time.Time) (*ExpiryCheck, error) {
+	if len(chain.Certificates) == 0 {
+		return nil, fmt.Errorf("no certificates to check")
+	}
+
+	check := &ExpiryCheck{Status: CheckOK}
+	var earliest *CertInfo
+	var earliestLeft time.Duration
+
+	for i := range chain.Certificates {
+		cert := &chain.Certificates[i]
+		notAfter, err := time.Parse(time.RFC3339, cert.NotAfter)
+		if err != nil {
+			return nil, fmt.Errorf("invalid NotAfter for %s: %w", cert.Subject, err)
+		}
+
+		left := notAfter.Sub(now)
+		status := CheckOK
+		switch {
+		case left <= crit:
+			status = CheckCritical
+		case left <= warn:
+			status = CheckWarning
+		}
+		if status > check.Status {
+			check.Status = status
+		}
+		if earliest == nil || left < earliestLeft {
+			earliest, earliestLeft = cert, left
+		}
+
+		check.Perfdata = append(check.Perfdata, fmt.Sprintf("'%s:%s_days'=%d;%d;%d;0;",
+			cert.Type, perfLabel(cert), days(left), days(warn), days(crit)))
+	}
+
+	expiry := fmt.Sprintf("expires in %d days", days(earliestLeft))
+	if earliestLeft < 0 {
+		expiry = fmt.Sprintf("expired %d days ago", -days(earliestLeft))
+	}
+	check.Summary = fmt.Sprintf("%s %s %s (%s), %d certificates checked",
+		earliest.Type, earliest.Subject, expiry, earliest.NotAfter, len(chain.Certificates))
+
+	return check, nil
+}
+
+// days converts d to whole days, truncating towards zero.
+func days(d time.Duration) int {
+	return int(d / (24 * time.Hour))
+}
+
+// perfLabel returns a perfdata-safe label for cert. Quotes and equals signs
+// are not allowed in Nagios labels.
+func perfLabel(cert *CertInfo) string {
+	label := cert.CommonName
+	if label == "" {
+		label = cert.Subject
+	}
+	return strings.NewReplacer("'", "", "=", "_").Replace(label)
+}
@@ -0,0 +1,80 @@
+package tlsquery
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckExpiry(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	warn, crit := 30*day, 7*day
+
+	chainExpiring := func(left ...time.Duration) *ChainInfo {
+		chain := &ChainInfo{}
+		for i, d := range left {
+			certType := "intermediate"
+			if i == 0 {
+				certType = "leaf"
+			}
+			chain.Certificates = append(chain.Certificates, CertInfo{
+				Type:       certType,
+				Subject:    "CN=test",
+				CommonName: "test",
+				NotAfter:   now.Add(d).Format(time.RFC3339),
+			})
+		}
+		return chain
+	}
+
+	tests := []struct {
+		name        string
+		chain       *ChainInfo
+		want        CheckStatus
+		wantSummary string
+	}{
+		{"all valid", chainExpiring(90*day, 365*day), CheckOK, "expires in 90 days"},
+		{"leaf within warning", chainExpiring(20*day, 365*day), CheckWarning, "expires in 20 days"},
+		{"intermediate within critical", chainExpiring(90*day, 3*day), CheckCritical, "expires in 3 days"},
+		{"exactly at critical", chainExpiring(7*day, 365*day), CheckCritical, "expires in 7 days"},
+		{"expired", chainExpiring(-2*day, 365*day), CheckCritical, "expired 2 days ago"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CheckExpiry(tt.chain, warn, crit, now)
+			if err != nil {
+				t.Fatalf("CheckExpiry() unexpected error: %v", err)
+			}
+			if got.Status != tt.want {
+				t.Errorf("Status = %s, want %s", got.Status, tt.want)
+			}
+			if !strings.Contains(got.Summary, tt.wantSummary) {
+				t.Errorf("Summary = %q, want to contain %q", got.Summary, tt.wantSummary)
+			}
+			if len(got.Perfdata) != len(tt.chain.Certificates) {
+				t.Errorf("got %d perfdata entries, want %d", len(got.Perfdata), len(tt.chain.Certificates))
+			}
+		})
+	}
+}
+
+func TestCheckExpiry_InvalidNotAfter(t *testing.T) {
+	chain := &ChainInfo{Certificates: []CertInfo{{NotAfter: "tomorrow"}}}
+	if _, err := CheckExpiry(chain, time.Hour, time.Minute, time.Now()); err == nil {
+		t.Error("expected error for unparsable NotAfter")
+	}
+}
+
+func TestExpiryCheckString(t *testing.T) {
+	check := &ExpiryCheck{
+		Status:   CheckWarning,
+		Summary:  "leaf CN=test expires in 20 days",
+		Perfdata: []string{"'leaf:test_days'=20;30;7;0;"},
+	}
+	want := "TLS WARNING - leaf CN=test expires in 20 days | 'leaf:test_days'=20;30;7;0;"
+	if got := check.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
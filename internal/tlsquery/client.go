@@ -0,0 +1,143 @@
+package tlsquery
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+)
+
+// QueryOptions controls how an endpoint is queried. The zero value connects
+// directly, sends the endpoint host as SNI and verifies against the system
+// roots.
+type QueryOptions struct {
+	// ServerName is sent as SNI and used for hostname verification. Empty
+	// uses the host part of the endpoint.
+	ServerName string
+	// RootCAs is used to verify the served chain. Nil uses the system roots.
+	RootCAs *x509.CertPool
+	// Certificates are offered when the server requests a client certificate.
+	Certificates []tls.Certificate
+	// StartTLS names the plaintext protocol to upgrade from before the TLS
+	// handshake (smtp, imap, pop3, ftp, ldap, xmpp, postgres, mysql). Empty
+	// means direct TLS.
+	StartTLS string
+	// ALPN lists the application protocols offered in the handshake.
+	ALPN []string
+	// Timeout bounds the connect, STARTTLS negotiation and handshake.
+	// Zero means no timeout beyond the context deadline.
+	Timeout time.Duration
+	// Dialer opens the TCP connection to the endpoint or proxy. Nil uses a
+	// net.Dialer.
+	Dialer ContextDialer
+	// Proxy tunnels the connection through an HTTP CONNECT (http://) or
+	// SOCKS5 proxy. socks5:// resolves the endpoint locally, socks5h://
+	// lets the proxy resolve it. User info in the URL is used for
+	// authentication.
+	Proxy *url.URL
+	// CheckResumption makes a second connection to find out whether the
+	// server resumes sessions. It adds up to a second for TLS 1.3 servers.
+	CheckResumption bool
+	// FetchIssuers completes chains that do not build to a trusted root by
+	// downloading missing intermediates from the AIA caIssuers URLs.
+	FetchIssuers bool
+}
+
+// ContextDialer dials network connections.
+type ContextDialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// Client queries TLS endpoints with a fixed set of options. A Client holds
+// no connection state and is safe for concurrent use.
+type Client struct {
+	Options QueryOptions
+}
+
+// NewClient returns a Client using opts for every request.
+func NewClient(opts QueryOptions) *Client {
+	return &Client{Options: opts}
+}
+
+// Query retrieves certificate chain information for endpoint.
+func (c *Client) Query(ctx context.Context, endpoint string) (*ChainInfo, error) {
+	return QueryContext(ctx, endpoint, c.Options)
+}
+
+// Scan enumerates the TLS versions and cipher suites accepted by endpoint.
+func (c *Client) Scan(ctx context.Context, endpoint string) (*ScanResult, error) {
+	return Scan(ctx, endpoint, c.Options)
+}
+
+// clientConfig returns the TLS configuration used to connect to endpoint.
+// Verification is disabled so that broken chains can still be inspected.
+func clientConfig(endpoint string, opts QueryOptions) *tls.Config {
+	serverName := opts.ServerName
+	if serverName == "" {
+		serverName, _, _ = net.SplitHostPort(endpoint)
+	}
+	return &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true,
+		NextProtos:         opts.ALPN,
+		Certificates:       opts.Certificates,
+	}
+}
+
+// handshake dials endpoint, runs the optional STARTTLS negotiation and
+// completes a TLS handshake using config.
+func handshake(ctx context.Context, endpoint string, config *tls.Config, opts QueryOptions) (*tls.Conn, error) {
+	return handshakeWith(ctx, endpoint, config, opts, nil)
+}
+
+// handshakeWith is like handshake, but runs the TLS handshake over the
+// connection returned by wrap if it is not nil.
+func handshakeWith(ctx context.Context, endpoint string, config *tls.Config, opts QueryOptions, wrap func(net.Conn) net.Conn) (*tls.Conn, error) {
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	rawConn, err := dial(ctx, endpoint, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+
+	// Unblock the plaintext negotiation and handshake when ctx is done.
+	stop := context.AfterFunc(ctx, func() {
+		rawConn.SetDeadline(time.Unix(1, 0))
+	})
+	defer stop()
+
+	if opts.StartTLS != "" {
+		if err := startTLS(rawConn, opts.StartTLS, config.ServerName); err != nil {
+			rawConn.Close()
+			return nil, contextError(ctx, err)
+		}
+	}
+
+	tlsConn := rawConn
+	if wrap != nil {
+		tlsConn = wrap(rawConn)
+	}
+	conn := tls.Client(tlsConn, config)
+	if err := conn.HandshakeContext(ctx); err != nil {
+		rawConn.Close()
+		return nil, fmt.Errorf("TLS handshake failed: %w", contextError(ctx, err))
+	}
+	return conn, nil
+}
+
+// contextError attaches the context error to an I/O error it caused, so
+// callers can match it with errors.Is.
+func contextError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil && !errors.Is(err, ctxErr) {
+		return fmt.Errorf("%v: %w", err, ctxErr)
+	}
+	return err
+}
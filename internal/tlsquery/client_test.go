@@ -0,0 +1,140 @@
+package tlsquery
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func serverCertificate(cert *testCert) tls.Certificate {
+	return tls.Certificate{
+		Certificate: [][]byte{cert.cert.Raw},
+		PrivateKey:  cert.key,
+	}
+}
+
+func TestClient_ConcurrentQueries(t *testing.T) {
+	root := newTestCert(t, caTemplate("Test Root"), nil)
+	leaf := newTestCert(t, leafTemplate("localhost"), root)
+	server, addr := startTLSServerWithConfig(t, &tls.Config{
+		Certificates: []tls.Certificate{serverCertificate(leaf)},
+	})
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root.cert)
+	client := NewClient(QueryOptions{ServerName: "localhost", RootCAs: roots, Timeout: 5 * time.Second})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			chain, err := client.Query(context.Background(), addr)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if !chain.Verification.Verified {
+				errs <- errors.New(chain.Verification.Error)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("concurrent query failed: %v", err)
+	}
+}
+
+func TestQueryContext_Canceled(t *testing.T) {
+	server, addr := startTestTLSServer(t, false)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := QueryContext(ctx, addr, QueryOptions{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestQueryContext_Deadline(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	// Accept connections but never answer the handshake.
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	_, err = QueryContext(ctx, listener.Addr().String(), QueryOptions{})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+type countingDialer struct {
+	calls int32
+}
+
+func (d *countingDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	atomic.AddInt32(&d.calls, 1)
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, network, address)
+}
+
+func TestQueryContext_Dialer(t *testing.T) {
+	server, addr := startTestTLSServer(t, false)
+	defer server.Close()
+
+	dialer := &countingDialer{}
+	if _, err := QueryContext(context.Background(), addr, QueryOptions{Dialer: dialer}); err != nil {
+		t.Fatalf("QueryContext failed: %v", err)
+	}
+	if dialer.calls != 1 {
+		t.Errorf("expected custom dialer to be used once, got %d calls", dialer.calls)
+	}
+}
+
+func TestQueryContext_ClientCertificate(t *testing.T) {
+	leaf := newTestCert(t, leafTemplate("localhost"), nil)
+	client := newTestCert(t, leafTemplate("client"), nil)
+
+	server, addr := startTLSServerWithConfig(t, &tls.Config{
+		Certificates: []tls.Certificate{serverCertificate(leaf)},
+		ClientAuth:   tls.RequireAnyClientCert,
+		MaxVersion:   tls.VersionTLS12,
+	})
+	defer server.Close()
+
+	if _, err := QueryContext(context.Background(), addr, QueryOptions{}); err == nil {
+		t.Error("expected handshake to fail without a client certificate")
+	}
+
+	opts := QueryOptions{Certificates: []tls.Certificate{serverCertificate(client)}}
+	if _, err := QueryContext(context.Background(), addr, opts); err != nil {
+		t.Errorf("QueryContext with client certificate failed: %v", err)
+	}
+}
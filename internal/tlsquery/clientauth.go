@@ -0,0 +1,98 @@
+package tlsquery
+
+import (
+	"crypto/tls"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+)
+
+// ClientCertRequest describes the CertificateRequest sent by a server that
+// asks for client authentication.
+type ClientCertRequest struct {
+	Version             string   `json:"version"`
+	AcceptableCAs       []string `json:"acceptable_cas,omitempty"`
+	SignatureAlgorithms []string `json:"signature_algorithms,omitempty"`
+	CertificateSent     bool     `json:"certificate_sent"`
+}
+
+// ClientAuthError is returned when the handshake fails after the server
+// requested a client certificate, typically because none or an unacceptable
+// one was sent.
+type ClientAuthError struct {
+	Request *ClientCertRequest
+	Err     error
+}
+
+func (e *ClientAuthError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ClientAuthError) Unwrap() error {
+	return e.Err
+}
+
+// recordClientCertRequest makes config select a client certificate from
+// certs the way crypto/tls does, and stores the server's request in
+// *request when one is received.
+func recordClientCertRequest(config *tls.Config, certs []tls.Certificate, request **ClientCertRequest) {
+	config.Certificates = nil
+	config.GetClientCertificate = func(cri *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		req := ClientCertRequestFromInfo(cri)
+		*request = req
+		for i := range certs {
+			if cri.SupportsCertificate(&certs[i]) == nil {
+				req.CertificateSent = true
+				return &certs[i], nil
+			}
+		}
+		return &tls.Certificate{}, nil
+	}
+}
+
+// ClientCertRequestFromInfo creates a ClientCertRequest from a
+// tls.CertificateRequestInfo.
+func ClientCertRequestFromInfo(cri *tls.CertificateRequestInfo) *ClientCertRequest {
+	req := &ClientCertRequest{Version: tls.VersionName(cri.Version)}
+	for _, raw := range cri.AcceptableCAs {
+		req.AcceptableCAs = append(req.AcceptableCAs, formatDistinguishedName(raw))
+	}
+	for _, scheme := range cri.SignatureSchemes {
+		req.SignatureAlgorithms = append(req.SignatureAlgorithms, signatureSchemeName(scheme))
+	}
+	return req
+}
+
+// extraSignatureSchemes names schemes that servers commonly offer but
+// crypto/tls does not implement, using their IANA names.
+var extraSignatureSchemes = map[tls.SignatureScheme]string{
+	0x0301: "rsa_pkcs1_sha224",
+	0x0302: "dsa_sha224",
+	0x0303: "ecdsa_sha224",
+	0x0402: "dsa_sha256",
+	0x0502: "dsa_sha384",
+	0x0602: "dsa_sha512",
+	0x0808: "ed448",
+	0x0809: "rsa_pss_pss_sha256",
+	0x080a: "rsa_pss_pss_sha384",
+	0x080b: "rsa_pss_pss_sha512",
+}
+
+func signatureSchemeName(scheme tls.SignatureScheme) string {
+	if name, ok := extraSignatureSchemes[scheme]; ok {
+		return name
+	}
+	return scheme.String()
+}
+
+// formatDistinguishedName decodes a DER-encoded distinguished name. Names
+// that fail to parse are shown in hex so they can still be compared.
+func formatDistinguishedName(der []byte) string {
+	var rdn pkix.RDNSequence
+	if rest, err := asn1.Unmarshal(der, &rdn); err != nil || len(rest) > 0 {
+		return fmt.Sprintf("invalid DN (%x)", der)
+	}
+	var name pkix.Name
+	name.FillFromRDNSequence(&rdn)
+	return name.String()
+}
@@ -0,0 +1,158 @@
+package tlsquery
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestQueryContext_ClientCertRequest(t *testing.T) {
+	leaf := newTestCert(t, leafTemplate("localhost"), nil)
+	clientCA := newTestCert(t, caTemplate("Test Client CA"), nil)
+
+	clientTmpl := leafTemplate("client")
+	clientTmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	trusted := newTestCert(t, clientTmpl, clientCA)
+	untrusted := newTestCert(t, clientTmpl, nil)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(clientCA.cert)
+	server, addr := startTLSServerWithConfig(t, &tls.Config{
+		Certificates: []tls.Certificate{serverCertificate(leaf)},
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+		ClientCAs:    pool,
+	})
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		cert     *testCert
+		wantSent bool
+	}{
+		{"acceptable certificate", trusted, true},
+		{"certificate from another CA", untrusted, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := QueryOptions{Certificates: []tls.Certificate{serverCertificate(tt.cert)}}
+			chain, err := QueryContext(context.Background(), addr, opts)
+			if err != nil {
+				t.Fatalf("QueryContext failed: %v", err)
+			}
+
+			req := chain.ClientCertRequest
+			if req == nil {
+				t.Fatal("expected the certificate request to be recorded")
+			}
+			if len(req.AcceptableCAs) != 1 || req.AcceptableCAs[0] != "CN=Test Client CA" {
+				t.Errorf("unexpected acceptable CAs %q", req.AcceptableCAs)
+			}
+			if len(req.SignatureAlgorithms) == 0 {
+				t.Error("expected signature algorithms")
+			}
+			if req.CertificateSent != tt.wantSent {
+				t.Errorf("CertificateSent = %t, want %t", req.CertificateSent, tt.wantSent)
+			}
+		})
+	}
+}
+
+func TestQueryContext_NoClientCertRequest(t *testing.T) {
+	server, addr := startTestTLSServer(t, false)
+	defer server.Close()
+
+	chain, err := QueryContext(context.Background(), addr, QueryOptions{})
+	if err != nil {
+		t.Fatalf("QueryContext failed: %v", err)
+	}
+	if chain.ClientCertRequest != nil {
+		t.Errorf("unexpected certificate request %+v", chain.ClientCertRequest)
+	}
+}
+
+func TestQueryContext_ClientCertRequestWithoutCertificate(t *testing.T) {
+	leaf := newTestCert(t, leafTemplate("localhost"), nil)
+	clientCA := newTestCert(t, caTemplate("Test Client CA"), nil)
+	pool := x509.NewCertPool()
+	pool.AddCert(clientCA.cert)
+
+	// A TLS 1.3 client completes its handshake before the server checks the
+	// client certificate, so the query succeeds. With TLS 1.2 the server
+	// aborts the handshake.
+	tests := []struct {
+		name        string
+		version     uint16
+		wantVersion string
+		wantError   bool
+	}{
+		{"TLS 1.3", tls.VersionTLS13, "TLS 1.3", false},
+		{"TLS 1.2", tls.VersionTLS12, "TLS 1.2", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, addr := startTLSServerWithConfig(t, &tls.Config{
+				Certificates: []tls.Certificate{serverCertificate(leaf)},
+				ClientAuth:   tls.RequireAndVerifyClientCert,
+				ClientCAs:    pool,
+				MaxVersion:   tt.version,
+			})
+			defer server.Close()
+
+			var req *ClientCertRequest
+			chain, err := QueryContext(context.Background(), addr, QueryOptions{})
+			if tt.wantError {
+				var authErr *ClientAuthError
+				if !errors.As(err, &authErr) {
+					t.Fatalf("expected *ClientAuthError, got %v", err)
+				}
+				req = authErr.Request
+			} else {
+				if err != nil {
+					t.Fatalf("QueryContext failed: %v", err)
+				}
+				req = chain.ClientCertRequest
+			}
+
+			if req == nil {
+				t.Fatal("expected the certificate request to be recorded")
+			}
+			if req.Version != tt.wantVersion {
+				t.Errorf("Version = %q, want %q", req.Version, tt.wantVersion)
+			}
+			if len(req.AcceptableCAs) != 1 || req.AcceptableCAs[0] != "CN=Test Client CA" {
+				t.Errorf("unexpected acceptable CAs %q", req.AcceptableCAs)
+			}
+			if req.CertificateSent {
+				t.Error("expected no certificate to be sent")
+			}
+		})
+	}
+}
+
+func TestFormatDistinguishedName(t *testing.T) {
+	ca := newTestCert(t, caTemplate("Test CA"), nil)
+	if got := formatDistinguishedName(ca.cert.RawSubject); got != "CN=Test CA" {
+		t.Errorf("formatDistinguishedName = %q, want %q", got, "CN=Test CA")
+	}
+	if got := formatDistinguishedName([]byte{0x01, 0x02}); !strings.HasPrefix(got, "invalid DN") {
+		t.Errorf("expected invalid DN marker, got %q", got)
+	}
+}
+
+func TestSignatureSchemeName(t *testing.T) {
+	tests := map[tls.SignatureScheme]string{
+		tls.ECDSAWithP256AndSHA256: "ECDSAWithP256AndSHA256",
+		0x0808:                     "ed448",
+		0xfefe:                     "SignatureScheme(65278)",
+	}
+	for scheme, want := range tests {
+		if got := signatureSchemeName(scheme); got != want {
+			t.Errorf("signatureSchemeName(%#04x) = %q, want %q", uint16(scheme), got, want)
+		}
+	}
+}
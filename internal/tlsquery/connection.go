@@ -0,0 +1,57 @@
+package tlsquery
+
+import (
+	"context"
+	"crypto/tls"
+	"time"
+)
+
+// ConnectionInfo holds the negotiated parameters of a TLS connection.
+type ConnectionInfo struct {
+	Version       string `json:"version"`
+	CipherSuite   string `json:"cipher_suite"`
+	KeyExchange   string `json:"key_exchange,omitempty"`
+	ALPN          string `json:"alpn,omitempty"`
+	ServerName    string `json:"server_name,omitempty"`
+	OCSPStapled   bool   `json:"ocsp_stapled"`
+	SCTsDelivered bool   `json:"scts_delivered"`
+	// ResumptionSupported reports whether a second connection resumed the
+	// session of the first. It is nil unless QueryOptions.CheckResumption
+	// is set.
+	ResumptionSupported *bool `json:"resumption_supported,omitempty"`
+}
+
+// resumptionTicketWait is how long to wait for TLS 1.3 session tickets,
+// which the server sends after the handshake.
+const resumptionTicketWait = time.Second
+
+// ConnectionInfoFromState creates a ConnectionInfo from a tls.ConnectionState.
+func ConnectionInfoFromState(state tls.ConnectionState) *ConnectionInfo {
+	return &ConnectionInfo{
+		Version:       tls.VersionName(state.Version),
+		CipherSuite:   tls.CipherSuiteName(state.CipherSuite),
+		KeyExchange:   keyExchangeGroup(state),
+		ALPN:          state.NegotiatedProtocol,
+		ServerName:    state.ServerName,
+		OCSPStapled:   len(state.OCSPResponse) > 0,
+		SCTsDelivered: len(state.SignedCertificateTimestamps) > 0,
+	}
+}
+
+// checkResumption reconnects to endpoint with the session cache of config,
+// which conn was established with, and reports whether the session was
+// resumed.
+func checkResumption(ctx context.Context, conn *tls.Conn, endpoint string, config *tls.Config, opts QueryOptions) bool {
+	if conn.ConnectionState().Version == tls.VersionTLS13 {
+		// Session tickets are only processed while reading.
+		conn.SetReadDeadline(time.Now().Add(resumptionTicketWait))
+		conn.Read(make([]byte, 1))
+	}
+
+	resumed, err := handshake(ctx, endpoint, config, opts)
+	if err != nil {
+		return false
+	}
+	defer resumed.Close()
+	return resumed.ConnectionState().DidResume
+}
@@ -0,0 +1,118 @@
+package tlsquery
+
+import (
+	"context"
+	"crypto/tls"
+	"testing"
+)
+
+func TestQueryContext_Connection(t *testing.T) {
+	leaf := newTestCert(t, leafTemplate("localhost"), nil)
+	server, addr := startTLSServerWithConfig(t, &tls.Config{
+		Certificates: []tls.Certificate{{
+			Certificate: [][]byte{leaf.cert.Raw},
+			PrivateKey:  leaf.key,
+			OCSPStaple:  []byte{0x30, 0x00},
+		}},
+		NextProtos: []string{"h2", "http/1.1"},
+		MinVersion: tls.VersionTLS12,
+		MaxVersion: tls.VersionTLS12,
+	})
+	defer server.Close()
+
+	chain, err := QueryContext(context.Background(), addr, QueryOptions{
+		ServerName: "localhost",
+		ALPN:       []string{"http/1.1"},
+	})
+	if err != nil {
+		t.Fatalf("QueryContext failed: %v", err)
+	}
+
+	c := chain.Connection
+	if c == nil {
+		t.Fatal("expected connection info")
+	}
+	if c.Version != "TLS 1.2" {
+		t.Errorf("expected version 'TLS 1.2', got %q", c.Version)
+	}
+	if c.CipherSuite == "" {
+		t.Error("expected cipher suite name")
+	}
+	if c.ALPN != "http/1.1" {
+		t.Errorf("expected ALPN 'http/1.1', got %q", c.ALPN)
+	}
+	if c.ServerName != "localhost" {
+		t.Errorf("expected server name 'localhost', got %q", c.ServerName)
+	}
+	if !c.OCSPStapled {
+		t.Error("expected OCSP staple to be reported")
+	}
+	if c.SCTsDelivered {
+		t.Error("expected no SCTs")
+	}
+	if c.ResumptionSupported != nil {
+		t.Error("expected resumption to be untested")
+	}
+}
+
+func TestQueryContext_Resumption(t *testing.T) {
+	leaf := newTestCert(t, leafTemplate("localhost"), nil)
+
+	tests := []struct {
+		name   string
+		config *tls.Config
+		want   bool
+	}{
+		{"TLS 1.3 tickets", &tls.Config{MinVersion: tls.VersionTLS13}, true},
+		{"TLS 1.2 tickets", &tls.Config{MaxVersion: tls.VersionTLS12}, true},
+		{"tickets disabled", &tls.Config{MaxVersion: tls.VersionTLS12, SessionTicketsDisabled: true}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.Certificates = []tls.Certificate{{
+				Certificate: [][]byte{leaf.cert.Raw},
+				PrivateKey:  leaf.key,
+			}}
+			server, addr := startTLSServerWithConfig(t, tt.config)
+			defer server.Close()
+
+			chain, err := QueryContext(context.Background(), addr, QueryOptions{
+				ServerName:      "localhost",
+				CheckResumption: true,
+			})
+			if err != nil {
+				t.Fatalf("QueryContext failed: %v", err)
+			}
+
+			got := chain.Connection.ResumptionSupported
+			if got == nil {
+				t.Fatal("expected resumption to be tested")
+			}
+			if *got != tt.want {
+				t.Errorf("ResumptionSupported = %t, want %t", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConnectionInfoFromState(t *testing.T) {
+	info := ConnectionInfoFromState(tls.ConnectionState{
+		Version:                     tls.VersionTLS13,
+		CipherSuite:                 tls.TLS_AES_128_GCM_SHA256,
+		NegotiatedProtocol:          "h2",
+		SignedCertificateTimestamps: [][]byte{{0x00}},
+	})
+
+	if info.Version != "TLS 1.3" {
+		t.Errorf("Version = %q, want %q", info.Version, "TLS 1.3")
+	}
+	if info.CipherSuite != "TLS_AES_128_GCM_SHA256" {
+		t.Errorf("CipherSuite = %q, want %q", info.CipherSuite, "TLS_AES_128_GCM_SHA256")
+	}
+	if info.ALPN != "h2" {
+		t.Errorf("ALPN = %q, want %q", info.ALPN, "h2")
+	}
+	if !info.SCTsDelivered || info.OCSPStapled {
+		t.Errorf("unexpected flags: %+v", info)
+	}
+}
@@ -0,0 +1,155 @@
+package tlsquery
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// CRLResult holds the revocation status of a certificate according to a CRL.
+type CRLResult struct {
+	Source           string `json:"source"`
+	Status           string `json:"status,omitempty"`
+	RevokedAt        string `json:"revoked_at,omitempty"`
+	RevocationReason string `json:"revocation_reason,omitempty"`
+	ThisUpdate       string `json:"this_update,omitempty"`
+	NextUpdate       string `json:"next_update,omitempty"`
+	Stale            bool   `json:"stale"`
+	Error            string `json:"error,omitempty"`
+}
+
+// localCRL is a CRL loaded from a file.
+type localCRL struct {
+	path string
+	list *x509.RevocationList
+}
+
+// CheckCRL checks every certificate in chain against a CRL and stores the
+// result in its CRL field. A CRL from crlFiles is used when it was signed by
+// the certificate's issuer; otherwise the first CRL distribution point of the
+// certificate is downloaded. Self-signed certificates are not checked. A nil
+// client uses http.DefaultClient.
+func CheckCRL(ctx context.Context, chain *ChainInfo, client *http.Client, crlFiles []string) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	var local []localCRL
+	for _, path := range crlFiles {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+		list, err := parseCRL(data)
+		if err != nil {
+			return fmt.Errorf("invalid CRL %s: %w", path, err)
+		}
+		local = append(local, localCRL{path: path, list: list})
+	}
+
+	downloaded := map[string]*x509.RevocationList{}
+	now := time.Now()
+	for i, cert := range chain.certs {
+		if bytes.Equal(cert.RawIssuer, cert.RawSubject) {
+			continue
+		}
+		issuer := findIssuer(cert, chain.certs)
+
+		var result *CRLResult
+		var list *x509.RevocationList
+		for _, l := range local {
+			if issuer != nil && l.list.CheckSignatureFrom(issuer) == nil {
+				result, list = &CRLResult{Source: l.path}, l.list
+				break
+			}
+		}
+		if result == nil {
+			if len(cert.CRLDistributionPoints) == 0 {
+				continue
+			}
+			url := cert.CRLDistributionPoints[0]
+			result = &CRLResult{Source: url}
+			if issuer == nil {
+				result.Error = "issuer not found in chain"
+				chain.Certificates[i].CRL = result
+				continue
+			}
+
+			list = downloaded[url]
+			if list == nil {
+				var err error
+				list, err = fetchCRL(ctx, client, url)
+				if err != nil {
+					result.Error = err.Error()
+					chain.Certificates[i].CRL = result
+					continue
+				}
+				downloaded[url] = list
+			}
+			if err := list.CheckSignatureFrom(issuer); err != nil {
+				result.Error = fmt.Sprintf("CRL signature verification failed: %v", err)
+				chain.Certificates[i].CRL = result
+				continue
+			}
+		}
+
+		chain.Certificates[i].CRL = result
+		fillCRLResult(result, list, cert, now)
+	}
+	return nil
+}
+
+func fillCRLResult(result *CRLResult, list *x509.RevocationList, cert *x509.Certificate, now time.Time) {
+	result.ThisUpdate = formatTime(list.ThisUpdate)
+	result.NextUpdate = formatTime(list.NextUpdate)
+	result.Stale = !list.NextUpdate.IsZero() && now.After(list.NextUpdate)
+
+	result.Status = "good"
+	for _, entry := range list.RevokedCertificateEntries {
+		if entry.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+			result.Status = "revoked"
+			result.RevokedAt = formatTime(entry.RevocationTime)
+			result.RevocationReason = revocationReason(entry.ReasonCode)
+			return
+		}
+	}
+}
+
+// fetchCRL downloads and parses the CRL at url.
+func fetchCRL(ctx context.Context, client *http.Client, url string) (*x509.RevocationList, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CRL URL: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("CRL download failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("CRL download returned HTTP %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRevocationResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CRL: %w", err)
+	}
+	list, err := parseCRL(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CRL: %w", err)
+	}
+	return list, nil
+}
+
+// parseCRL parses a DER or PEM encoded CRL.
+func parseCRL(data []byte) (*x509.RevocationList, error) {
+	if block, _ := pem.Decode(data); block != nil && block.Type == "X509 CRL" {
+		data = block.Bytes
+	}
+	return x509.ParseRevocationList(data)
+}
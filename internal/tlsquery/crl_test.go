@@ -0,0 +1,140 @@
+package tlsquery
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newCRL(t *testing.T, issuer *testCert, nextUpdate time.Time, revoked ...x509.RevocationListEntry) []byte {
+	t.Helper()
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:                    big.NewInt(1),
+		ThisUpdate:                time.Now().Add(-time.Hour),
+		NextUpdate:                nextUpdate,
+		RevokedCertificateEntries: revoked,
+	}, issuer.cert, issuer.key)
+	if err != nil {
+		t.Fatalf("failed to create CRL: %v", err)
+	}
+	return der
+}
+
+func serveCRL(t *testing.T, crl []byte) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pkix-crl")
+		w.Write(crl)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL + "/root.crl"
+}
+
+func TestCheckCRL(t *testing.T) {
+	root := newTestCert(t, caTemplate("Test Root"), nil)
+	other := newTestCert(t, caTemplate("Other Root"), nil)
+	revokedAt := time.Now().Add(-2 * time.Hour).UTC().Truncate(time.Second)
+
+	current := newCRL(t, root, time.Now().Add(time.Hour), x509.RevocationListEntry{
+		SerialNumber:   big.NewInt(2),
+		RevocationTime: revokedAt,
+		ReasonCode:     1,
+	})
+	currentURL := serveCRL(t, current)
+	staleURL := serveCRL(t, newCRL(t, root, time.Now().Add(-time.Minute)))
+	forgedURL := serveCRL(t, newCRL(t, other, time.Now().Add(time.Hour)))
+
+	localPath := filepath.Join(t.TempDir(), "root.crl")
+	if err := os.WriteFile(localPath, pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: current}), 0o600); err != nil {
+		t.Fatalf("failed to write CRL: %v", err)
+	}
+
+	newLeaf := func(serial int64, dp string) *x509.Certificate {
+		tmpl := leafTemplate("test.example.com")
+		tmpl.SerialNumber = big.NewInt(serial)
+		if dp != "" {
+			tmpl.CRLDistributionPoints = []string{dp}
+		}
+		return newTestCert(t, tmpl, root).cert
+	}
+
+	tests := []struct {
+		name       string
+		leaf       *x509.Certificate
+		files      []string
+		wantSource string
+		wantStatus string
+		wantReason string
+		wantStale  bool
+		wantError  string
+	}{
+		{name: "good", leaf: newLeaf(1, currentURL), wantSource: currentURL, wantStatus: "good"},
+		{name: "revoked", leaf: newLeaf(2, currentURL), wantSource: currentURL, wantStatus: "revoked", wantReason: "keyCompromise"},
+		{name: "stale", leaf: newLeaf(1, staleURL), wantSource: staleURL, wantStatus: "good", wantStale: true},
+		{name: "wrong signer", leaf: newLeaf(1, forgedURL), wantSource: forgedURL, wantError: "signature verification failed"},
+		{name: "unreachable", leaf: newLeaf(1, "http://127.0.0.1:1/root.crl"), wantSource: "http://127.0.0.1:1/root.crl", wantError: "download failed"},
+		{name: "local file", leaf: newLeaf(2, ""), files: []string{localPath}, wantSource: localPath, wantStatus: "revoked", wantReason: "keyCompromise"},
+		{name: "local file preferred", leaf: newLeaf(2, forgedURL), files: []string{localPath}, wantSource: localPath, wantStatus: "revoked", wantReason: "keyCompromise"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := &ChainInfo{certs: []*x509.Certificate{tt.leaf, root.cert}}
+			for _, cert := range chain.certs {
+				chain.Certificates = append(chain.Certificates, CertInfoFromCert(cert))
+			}
+
+			if err := CheckCRL(context.Background(), chain, nil, tt.files); err != nil {
+				t.Fatalf("CheckCRL failed: %v", err)
+			}
+			if chain.Certificates[1].CRL != nil {
+				t.Error("self-signed root should not be checked")
+			}
+
+			result := chain.Certificates[0].CRL
+			if result == nil {
+				t.Fatal("expected a CRL result for the leaf")
+			}
+			if result.Source != tt.wantSource {
+				t.Errorf("Source = %q, want %q", result.Source, tt.wantSource)
+			}
+			if tt.wantError != "" {
+				if !strings.Contains(result.Error, tt.wantError) {
+					t.Errorf("Error = %q, want it to contain %q", result.Error, tt.wantError)
+				}
+				return
+			}
+			if result.Error != "" {
+				t.Fatalf("unexpected error: %s", result.Error)
+			}
+			if result.Status != tt.wantStatus || result.RevocationReason != tt.wantReason || result.Stale != tt.wantStale {
+				t.Errorf("got status %q, reason %q, stale %t; want %q, %q, %t",
+					result.Status, result.RevocationReason, result.Stale, tt.wantStatus, tt.wantReason, tt.wantStale)
+			}
+			if tt.wantStatus == "revoked" && result.RevokedAt != formatTime(revokedAt) {
+				t.Errorf("RevokedAt = %q, want %q", result.RevokedAt, formatTime(revokedAt))
+			}
+			if result.ThisUpdate == "" || result.NextUpdate == "" {
+				t.Error("expected thisUpdate and nextUpdate")
+			}
+		})
+	}
+}
+
+func TestCheckCRL_InvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.crl")
+	os.WriteFile(path, []byte("not a CRL"), 0o600)
+
+	if err := CheckCRL(context.Background(), &ChainInfo{}, nil, []string{path}); err == nil {
+		t.Error("expected error for invalid CRL file")
+	}
+}
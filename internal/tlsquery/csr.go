@@ -0,0 +1,271 @@
+package tlsquery
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"unicode/utf8"
+)
+
+// CSRInfo holds the metadata of a PKCS#10 certificate signing request. The
+// fields mirror CertInfo; the requested key usages, basic constraints and
+// key ID are read from the extension request attribute.
+type CSRInfo struct {
+	Version            int               `json:"version"`
+	SignatureAlgorithm string            `json:"signature_algorithm"`
+	SignatureValid     bool              `json:"signature_valid"`
+	SignatureError     string            `json:"signature_error,omitempty"`
+	Subject            string            `json:"subject"`
+	CommonName         string            `json:"common_name"`
+	PublicKeyAlgorithm string            `json:"public_key_algorithm"`
+	PublicKey          *PublicKeyInfo    `json:"public_key"`
+	KeyUsage           []string          `json:"key_usage,omitempty"`
+	ExtKeyUsage        []string          `json:"extended_key_usage,omitempty"`
+	BasicConstraints   *BasicConstraints `json:"basic_constraints,omitempty"`
+	SubjectKeyID       string            `json:"subject_key_id,omitempty"`
+	SubjectAltNames    []string          `json:"subject_alternative_names,omitempty"`
+	EmailAddresses     []string          `json:"email_addresses,omitempty"`
+	IPAddresses        []string          `json:"ip_addresses,omitempty"`
+	URIs               []string          `json:"uris,omitempty"`
+	Extensions         []CSRExtension    `json:"extensions,omitempty"`
+	Attributes         []CSRAttribute    `json:"attributes,omitempty"`
+	Fingerprint        Fingerprint       `json:"fingerprint"`
+	PEM                string            `json:"pem,omitempty"`
+}
+
+// CSRExtension is an extension requested in a CSR.
+type CSRExtension struct {
+	OID      string `json:"oid"`
+	Name     string `json:"name,omitempty"`
+	Critical bool   `json:"critical"`
+}
+
+// CSRAttribute is a PKCS#9 attribute of a CSR. String values are shown as
+// text, other values as hex-encoded DER. The values of the extension
+// request are the OIDs of the requested extensions.
+type CSRAttribute struct {
+	OID    string   `json:"oid"`
+	Name   string   `json:"name,omitempty"`
+	Values []string `json:"values"`
+}
+
+var oidExtensionRequest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 14}
+
+var csrAttributeNames = map[string]string{
+	"1.2.840.113549.1.9.2":   "unstructuredName",
+	"1.2.840.113549.1.9.7":   "challengePassword",
+	"1.2.840.113549.1.9.14":  "extensionRequest",
+	"1.3.6.1.4.1.311.13.2.3": "osVersion",
+	"1.3.6.1.4.1.311.21.20":  "requestClientInfo",
+	"1.3.6.1.4.1.311.13.2.2": "enrollmentCSP",
+}
+
+var extensionNames = map[string]string{
+	"2.5.29.14":               "Subject Key Identifier",
+	"2.5.29.15":               "Key Usage",
+	"2.5.29.17":               "Subject Alternative Name",
+	"2.5.29.19":               "Basic Constraints",
+	"2.5.29.32":               "Certificate Policies",
+	"2.5.29.35":               "Authority Key Identifier",
+	"2.5.29.37":               "Extended Key Usage",
+	"1.3.6.1.5.5.7.1.24":      "TLS Feature",
+	"1.3.6.1.4.1.311.20.2":    "Certificate Template Name",
+	"1.3.6.1.4.1.311.21.7":    "Certificate Template",
+	"1.3.6.1.4.1.11129.2.4.2": "CT Precertificate SCTs",
+}
+
+var extKeyUsageOIDs = map[string]x509.ExtKeyUsage{
+	"1.3.6.1.5.5.7.3.1": x509.ExtKeyUsageServerAuth,
+	"1.3.6.1.5.5.7.3.2": x509.ExtKeyUsageClientAuth,
+	"1.3.6.1.5.5.7.3.3": x509.ExtKeyUsageCodeSigning,
+	"1.3.6.1.5.5.7.3.4": x509.ExtKeyUsageEmailProtection,
+	"1.3.6.1.5.5.7.3.8": x509.ExtKeyUsageTimeStamping,
+	"1.3.6.1.5.5.7.3.9": x509.ExtKeyUsageOCSPSigning,
+}
+
+// ParseCSRFile reads a file holding certificate signing requests.
+func ParseCSRFile(path string) ([]CSRInfo, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	return ParseCSR(data)
+}
+
+// ParseCSR parses PEM CERTIFICATE REQUEST blocks, or a single DER-encoded
+// request when data holds no PEM blocks.
+func ParseCSR(data []byte) ([]CSRInfo, error) {
+	var csrs []CSRInfo
+
+	block, rest := pem.Decode(data)
+	if block == nil {
+		csr, err := x509.ParseCertificateRequest(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate request: %w", err)
+		}
+		return []CSRInfo{CSRInfoFromRequest(csr)}, nil
+	}
+	for ; block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE REQUEST" && block.Type != "NEW CERTIFICATE REQUEST" {
+			continue
+		}
+		csr, err := x509.ParseCertificateRequest(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate request: %w", err)
+		}
+		csrs = append(csrs, CSRInfoFromRequest(csr))
+	}
+
+	if len(csrs) == 0 {
+		return nil, fmt.Errorf("no certificate requests found")
+	}
+	return csrs, nil
+}
+
+// CSRInfoFromRequest creates a CSRInfo from an x509.CertificateRequest and
+// verifies its self-signature.
+func CSRInfoFromRequest(csr *x509.CertificateRequest) CSRInfo {
+	info := CSRInfo{
+		Version:            csr.Version + 1,
+		SignatureAlgorithm: csr.SignatureAlgorithm.String(),
+		Subject:            csr.Subject.String(),
+		CommonName:         csr.Subject.CommonName,
+		PublicKeyAlgorithm: csr.PublicKeyAlgorithm.String(),
+		PublicKey:          newPublicKeyInfo(csr.PublicKey, csr.PublicKeyAlgorithm, csr.RawSubjectPublicKeyInfo),
+		SubjectAltNames:    csr.DNSNames,
+		EmailAddresses:     csr.EmailAddresses,
+		IPAddresses:        formatIPs(csr.IPAddresses),
+		Fingerprint:        computeFingerprint(csr.Raw),
+		PEM:                string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr.Raw})),
+	}
+	for _, uri := range csr.URIs {
+		info.URIs = append(info.URIs, uri.String())
+	}
+
+	if err := csr.CheckSignature(); err != nil {
+		info.SignatureError = err.Error()
+	} else {
+		info.SignatureValid = true
+	}
+
+	for _, ext := range csr.Extensions {
+		oid := ext.Id.String()
+		info.Extensions = append(info.Extensions, CSRExtension{
+			OID:      oid,
+			Name:     extensionNames[oid],
+			Critical: ext.Critical,
+		})
+		switch oid {
+		case "2.5.29.14":
+			var id []byte
+			if _, err := asn1.Unmarshal(ext.Value, &id); err == nil {
+				info.SubjectKeyID = formatKeyID(id)
+			}
+		case "2.5.29.15":
+			var bits asn1.BitString
+			if _, err := asn1.Unmarshal(ext.Value, &bits); err == nil {
+				var usage x509.KeyUsage
+				for i := 0; i < 9; i++ {
+					if bits.At(i) != 0 {
+						usage |= 1 << uint(i)
+					}
+				}
+				info.KeyUsage = formatKeyUsage(usage)
+			}
+		case "2.5.29.37":
+			var oids []asn1.ObjectIdentifier
+			if _, err := asn1.Unmarshal(ext.Value, &oids); err == nil {
+				for _, o := range oids {
+					if u, ok := extKeyUsageOIDs[o.String()]; ok {
+						info.ExtKeyUsage = append(info.ExtKeyUsage, formatExtKeyUsage([]x509.ExtKeyUsage{u})...)
+					} else {
+						info.ExtKeyUsage = append(info.ExtKeyUsage, o.String())
+					}
+				}
+			}
+		case "2.5.29.19":
+			var constraints struct {
+				IsCA       bool `asn1:"optional"`
+				MaxPathLen int  `asn1:"optional,default:-1"`
+			}
+			if _, err := asn1.Unmarshal(ext.Value, &constraints); err == nil {
+				info.BasicConstraints = &BasicConstraints{IsCA: constraints.IsCA, MaxPathLen: constraints.MaxPathLen}
+			}
+		}
+	}
+
+	info.Attributes = csrAttributes(csr.RawTBSCertificateRequest)
+	return info
+}
+
+type certificationRequestInfo struct {
+	Version       int
+	Subject       asn1.RawValue
+	PublicKey     asn1.RawValue
+	RawAttributes []asn1.RawValue `asn1:"tag:0"`
+}
+
+type csrAttribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue `asn1:"set"`
+}
+
+// csrAttributes decodes the attributes of a CertificationRequestInfo.
+// x509.CertificateRequest only exposes attributes holding
+// AttributeTypeAndValue sets, which excludes challengePassword.
+func csrAttributes(tbs []byte) []CSRAttribute {
+	var info certificationRequestInfo
+	if _, err := asn1.Unmarshal(tbs, &info); err != nil {
+		return nil
+	}
+
+	var attributes []CSRAttribute
+	for _, raw := range info.RawAttributes {
+		var attr csrAttribute
+		if _, err := asn1.Unmarshal(raw.FullBytes, &attr); err != nil {
+			continue
+		}
+		a := CSRAttribute{
+			OID:    attr.Type.String(),
+			Name:   csrAttributeNames[attr.Type.String()],
+			Values: []string{},
+		}
+		for rest := attr.Values.Bytes; len(rest) > 0; {
+			var value asn1.RawValue
+			var err error
+			if rest, err = asn1.Unmarshal(rest, &value); err != nil {
+				break
+			}
+			if attr.Type.Equal(oidExtensionRequest) {
+				var extensions []pkix.Extension
+				if _, err := asn1.Unmarshal(value.FullBytes, &extensions); err == nil {
+					for _, ext := range extensions {
+						a.Values = append(a.Values, ext.Id.String())
+					}
+					continue
+				}
+			}
+			a.Values = append(a.Values, attributeValue(value))
+		}
+		attributes = append(attributes, a)
+	}
+	return attributes
+}
+
+// attributeValue returns string values as text and others as hex DER.
+func attributeValue(value asn1.RawValue) string {
+	if value.Class == asn1.ClassUniversal {
+		switch value.Tag {
+		case asn1.TagUTF8String, asn1.TagPrintableString, asn1.TagIA5String, asn1.TagT61String:
+			if utf8.Valid(value.Bytes) {
+				return string(value.Bytes)
+			}
+		}
+	}
+	return hex.EncodeToString(value.FullBytes)
+}
@@ -0,0 +1,112 @@
+package tlsquery
+
+import (
+	"bytes"
+	"encoding/pem"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testCSRPEM was created by openssl req with a challenge password, an
+// unstructured name and SAN, key usage, extended key usage and basic
+// constraints extensions.
+const testCSRPEM = `-----BEGIN CERTIFICATE REQUEST-----
+MIIBkTCCATgCAQAwLDEYMBYGA1UEAwwPd3d3LmV4YW1wbGUuY29tMRAwDgYDVQQK
+DAdFeGFtcGxlMFkwEwYHKoZIzj0CAQYIKoZIzj0DAQcDQgAERMXeBkK9QHzAc314
+rFqptObent+5Vm0YaOrTjuXj43zfqddjr/GfBzdX7fs3a3sIypwy/ctJ+hU3DkAS
+MPSk26CBqTAVBgkqhkiG9w0BCQcxCAwGczNjcmV0MBsGCSqGSIb3DQEJAjEODAxF
+eGFtcGxlIFVuaXQwcwYJKoZIhvcNAQkOMWYwZDAtBgNVHREEJjAkgg93d3cuZXhh
+bXBsZS5jb22CC2V4YW1wbGUuY29thwQKAAABMA4GA1UdDwEB/wQEAwIFoDAYBgNV
+HSUEETAPBggrBgEFBQcDAQYDKgMEMAkGA1UdEwQCMAAwCgYIKoZIzj0EAwIDRwAw
+RAIgUktD2dnB6QwVAFIz7TlWldATxuXgSQLJ5s9RDycgxCQCIEk5UZ7EJqPt3+eF
+9KX3Tn0j//rwulgA1nY5bYvBzVXp
+-----END CERTIFICATE REQUEST-----`
+
+func TestParseCSR(t *testing.T) {
+	csrs, err := ParseCSR([]byte(testCSRPEM))
+	if err != nil {
+		t.Fatalf("ParseCSR failed: %v", err)
+	}
+	if len(csrs) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(csrs))
+	}
+	csr := csrs[0]
+
+	if !csr.SignatureValid || csr.SignatureError != "" {
+		t.Errorf("expected a valid signature, got %q", csr.SignatureError)
+	}
+	if csr.Subject != "CN=www.example.com,O=Example" || csr.CommonName != "www.example.com" {
+		t.Errorf("unexpected subject %q", csr.Subject)
+	}
+	if pk := csr.PublicKey; csr.PublicKeyAlgorithm != "ECDSA" || pk == nil || pk.Bits != 256 || pk.Curve != "P-256" || pk.SPKISHA256Base64 == "" {
+		t.Errorf("unexpected public key %s %+v", csr.PublicKeyAlgorithm, pk)
+	}
+	if want := []string{"www.example.com", "example.com"}; !reflect.DeepEqual(csr.SubjectAltNames, want) {
+		t.Errorf("SubjectAltNames = %v, want %v", csr.SubjectAltNames, want)
+	}
+	if want := []string{"10.0.0.1"}; !reflect.DeepEqual(csr.IPAddresses, want) {
+		t.Errorf("IPAddresses = %v, want %v", csr.IPAddresses, want)
+	}
+	if want := []string{"Digital Signature", "Key Encipherment"}; !reflect.DeepEqual(csr.KeyUsage, want) {
+		t.Errorf("KeyUsage = %v, want %v", csr.KeyUsage, want)
+	}
+	if want := []string{"TLS Web Server Authentication", "1.2.3.4"}; !reflect.DeepEqual(csr.ExtKeyUsage, want) {
+		t.Errorf("ExtKeyUsage = %v, want %v", csr.ExtKeyUsage, want)
+	}
+	if csr.BasicConstraints == nil || csr.BasicConstraints.IsCA {
+		t.Errorf("expected CA:FALSE basic constraints, got %+v", csr.BasicConstraints)
+	}
+
+	wantExtensions := []CSRExtension{
+		{OID: "2.5.29.17", Name: "Subject Alternative Name"},
+		{OID: "2.5.29.15", Name: "Key Usage", Critical: true},
+		{OID: "2.5.29.37", Name: "Extended Key Usage"},
+		{OID: "2.5.29.19", Name: "Basic Constraints"},
+	}
+	if !reflect.DeepEqual(csr.Extensions, wantExtensions) {
+		t.Errorf("Extensions = %+v, want %+v", csr.Extensions, wantExtensions)
+	}
+
+	wantAttributes := []CSRAttribute{
+		{OID: "1.2.840.113549.1.9.7", Name: "challengePassword", Values: []string{"s3cret"}},
+		{OID: "1.2.840.113549.1.9.2", Name: "unstructuredName", Values: []string{"Example Unit"}},
+		{OID: "1.2.840.113549.1.9.14", Name: "extensionRequest", Values: []string{"2.5.29.17", "2.5.29.15", "2.5.29.37", "2.5.29.19"}},
+	}
+	if !reflect.DeepEqual(csr.Attributes, wantAttributes) {
+		t.Errorf("Attributes = %+v, want %+v", csr.Attributes, wantAttributes)
+	}
+}
+
+func TestParseCSR_DER(t *testing.T) {
+	block, _ := pem.Decode([]byte(testCSRPEM))
+	csrs, err := ParseCSR(block.Bytes)
+	if err != nil {
+		t.Fatalf("ParseCSR failed: %v", err)
+	}
+	if len(csrs) != 1 || csrs[0].CommonName != "www.example.com" {
+		t.Errorf("unexpected requests %+v", csrs)
+	}
+}
+
+func TestParseCSR_InvalidSignature(t *testing.T) {
+	block, _ := pem.Decode([]byte(testCSRPEM))
+	der := append([]byte{}, block.Bytes...)
+	// Change the subject, which is covered by the signature.
+	der[bytes.Index(der, []byte("www.example.com"))] = 'x'
+
+	csrs, err := ParseCSR(der)
+	if err != nil {
+		t.Fatalf("ParseCSR failed: %v", err)
+	}
+	if csrs[0].SignatureValid || csrs[0].SignatureError == "" {
+		t.Error("expected signature verification to fail")
+	}
+}
+
+func TestParseCSR_NoRequests(t *testing.T) {
+	_, err := ParseCSR([]byte(testCertPEM))
+	if err == nil || !strings.Contains(err.Error(), "no certificate requests found") {
+		t.Errorf("expected no certificate requests error, got %v", err)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build go1.25
+
+package tlsquery
+
+import "crypto/tls"
+
+// keyExchangeGroup returns the negotiated key exchange group, if any.
+func keyExchangeGroup(state tls.ConnectionState) string {
+	if state.CurveID == 0 {
+		return ""
+	}
+	return state.CurveID.String()
+}
@@ -0,0 +1,11 @@
+//go:build !go1.25
+
+package tlsquery
+
+import "crypto/tls"
+
+// keyExchangeGroup returns an empty string because tls.ConnectionState does
+// not expose the negotiated group before Go 1.25.
+func keyExchangeGroup(state tls.ConnectionState) string {
+	return ""
+}
@@ -0,0 +1,172 @@
+package tlsquery
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/tlsctl/internal/pkcs12"
+)
+
+// Private key encodings.
+const (
+	KeyFormatPKCS1 = "PKCS#1"
+	KeyFormatPKCS8 = "PKCS#8"
+	KeyFormatSEC1  = "SEC1"
+)
+
+// KeyInfo describes a private key. PublicKey describes its public half the
+// same way as for certificates, so the SPKI pins are the same for the key
+// and every certificate issued for it.
+type KeyInfo struct {
+	Format     string         `json:"format"`
+	Encryption string         `json:"encryption,omitempty"`
+	Algorithm  string         `json:"algorithm"`
+	PublicKey  *PublicKeyInfo `json:"public_key"`
+
+	// public is the public half of the key.
+	public crypto.PublicKey
+}
+
+// KeyMatch is the result of comparing a private key with a certificate.
+type KeyMatch struct {
+	Match                 bool   `json:"match"`
+	CertificateSubject    string `json:"certificate_subject"`
+	CertificateSPKISHA256 string `json:"certificate_spki_sha256"`
+	KeySPKISHA256         string `json:"key_spki_sha256"`
+}
+
+// ParsePrivateKeyFile reads a private key file. See ParsePrivateKey.
+func ParsePrivateKeyFile(path string, password func() (string, error)) (*KeyInfo, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	return ParsePrivateKey(data, password)
+}
+
+// ParsePrivateKey parses the first private key in data: a PEM PKCS#1 (RSA
+// PRIVATE KEY), SEC1 (EC PRIVATE KEY), PKCS#8 (PRIVATE KEY) or encrypted
+// PKCS#8 (ENCRYPTED PRIVATE KEY) block, or a DER key when data holds no PEM
+// blocks. password is only called for encrypted keys.
+func ParsePrivateKey(data []byte, password func() (string, error)) (*KeyInfo, error) {
+	block, rest := pem.Decode(data)
+	if block == nil {
+		return parseDERKey(data)
+	}
+
+	for ; block != nil; block, rest = pem.Decode(rest) {
+		if _, ok := block.Headers["DEK-Info"]; ok {
+			return nil, errors.New("legacy encrypted PEM keys are not supported, convert the key with openssl pkcs8 -topk8")
+		}
+
+		var key crypto.PrivateKey
+		var err error
+		info := &KeyInfo{}
+		switch block.Type {
+		case "RSA PRIVATE KEY":
+			info.Format = KeyFormatPKCS1
+			key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "EC PRIVATE KEY":
+			info.Format = KeyFormatSEC1
+			key, err = x509.ParseECPrivateKey(block.Bytes)
+		case "PRIVATE KEY":
+			info.Format = KeyFormatPKCS8
+			key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		case "ENCRYPTED PRIVATE KEY":
+			info.Format = KeyFormatPKCS8
+			var pass string
+			if pass, err = password(); err != nil {
+				return nil, err
+			}
+			var enc *pkcs12.Encryption
+			if key, enc, err = pkcs12.DecryptPrivateKey(block.Bytes, pass); err == nil {
+				info.Encryption = enc.Algorithm
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse private key: %w", err)
+		}
+		if err := describeKey(info, key); err != nil {
+			return nil, err
+		}
+		return info, nil
+	}
+
+	return nil, errors.New("no private key found")
+}
+
+// parseDERKey parses an unencrypted DER key in any of the supported
+// encodings.
+func parseDERKey(der []byte) (*KeyInfo, error) {
+	info := &KeyInfo{}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	info.Format = KeyFormatPKCS8
+	if err != nil {
+		key, err = x509.ParsePKCS1PrivateKey(der)
+		info.Format = KeyFormatPKCS1
+	}
+	if err != nil {
+		key, err = x509.ParseECPrivateKey(der)
+		info.Format = KeyFormatSEC1
+	}
+	if err != nil {
+		return nil, errors.New("no private key found: data is neither PEM nor a DER PKCS#1, PKCS#8 or SEC1 key")
+	}
+	if err := describeKey(info, key); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// describeKey fills in the algorithm and public key of key.
+func describeKey(info *KeyInfo, key crypto.PrivateKey) error {
+	var algorithm x509.PublicKeyAlgorithm
+	switch key.(type) {
+	case *rsa.PrivateKey:
+		algorithm = x509.RSA
+	case *ecdsa.PrivateKey:
+		algorithm = x509.ECDSA
+	case ed25519.PrivateKey:
+		algorithm = x509.Ed25519
+	default:
+		return fmt.Errorf("unsupported private key type %T", key)
+	}
+	info.Algorithm = algorithm.String()
+
+	info.public = key.(interface{ Public() crypto.PublicKey }).Public()
+	der, err := x509.MarshalPKIXPublicKey(info.public)
+	if err != nil {
+		return fmt.Errorf("failed to encode public key: %w", err)
+	}
+	info.PublicKey = newPublicKeyInfo(info.public, algorithm, der)
+	return nil
+}
+
+// MatchKey compares key with the leaf certificate of chain: the first
+// certificate on the path found by AnalyzeChain, or the first certificate.
+// The public keys are compared, so certificates whose SPKI encoding differs
+// from Go's still match; the SPKI fingerprints are informational.
+func MatchKey(key *KeyInfo, chain *ChainInfo) *KeyMatch {
+	leaf := chain.certs[0]
+	if chain.Analysis != nil && len(chain.Analysis.Path) > 0 {
+		leaf = chain.certs[chain.Analysis.Path[0]]
+	}
+
+	public, ok := key.public.(interface{ Equal(crypto.PublicKey) bool })
+	return &KeyMatch{
+		Match:                 ok && public.Equal(leaf.PublicKey),
+		CertificateSubject:    leaf.Subject.String(),
+		CertificateSPKISHA256: PublicKeyInfoFromCert(leaf).SPKISHA256,
+		KeySPKISHA256:         key.PublicKey.SPKISHA256,
+	}
+}
@@ -0,0 +1,59 @@
+package tlsquery
+
+import (
+	"time"
+
+	"github.com/tlsctl/internal/jks"
+)
+
+// KeyStoreInfo describes the contents of a Java key store. Partial is set
+// when the entries after a secret key could not be listed; Note says why.
+type KeyStoreInfo struct {
+	Type              string          `json:"type"`
+	Version           int             `json:"version"`
+	IntegrityVerified bool            `json:"integrity_verified"`
+	Entries           []KeyStoreEntry `json:"entries"`
+	Partial           bool            `json:"partial,omitempty"`
+	Note              string          `json:"note,omitempty"`
+}
+
+// KeyStoreEntry describes a single alias. Certificates holds the trusted
+// certificate, or the chain of a private key entry, leaf first.
+type KeyStoreEntry struct {
+	Alias        string     `json:"alias"`
+	Type         string     `json:"type"`
+	Created      string     `json:"created"`
+	Certificates []CertInfo `json:"certificates"`
+}
+
+// KeyStoreInfoFromStore describes a decoded JKS or JCEKS key store.
+func KeyStoreInfoFromStore(store *jks.KeyStore) *KeyStoreInfo {
+	info := &KeyStoreInfo{
+		Type:              store.Type,
+		Version:           store.Version,
+		IntegrityVerified: store.Verified,
+		Entries:           make([]KeyStoreEntry, 0, len(store.Entries)),
+		Partial:           store.Partial,
+		Note:              store.Note,
+	}
+
+	for _, e := range store.Entries {
+		entry := KeyStoreEntry{
+			Alias:        e.Alias,
+			Type:         e.Type,
+			Created:      e.Created.UTC().Format(time.RFC3339),
+			Certificates: make([]CertInfo, 0, len(e.Certificates)),
+		}
+		for i, cert := range e.Certificates {
+			certInfo := CertInfoFromCert(cert)
+			certInfo.Source = SourceFile
+			if e.Type == jks.PrivateKeyEntry {
+				certInfo.Type = certType(i, cert)
+			}
+			entry.Certificates = append(entry.Certificates, certInfo)
+		}
+		info.Entries = append(info.Entries, entry)
+	}
+
+	return info
+}
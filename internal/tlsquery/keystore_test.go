@@ -0,0 +1,51 @@
+package tlsquery
+
+import (
+	"crypto/x509"
+	"testing"
+	"time"
+
+	"github.com/tlsctl/internal/jks"
+)
+
+func TestKeyStoreInfoFromStore(t *testing.T) {
+	root := newTestCert(t, caTemplate("Test Root"), nil)
+	leaf := newTestCert(t, leafTemplate("localhost"), root)
+	created := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	store := &jks.KeyStore{
+		Type:     jks.TypeJKS,
+		Version:  2,
+		Verified: true,
+		Partial:  true,
+		Note:     "stopped",
+		Entries: []jks.Entry{
+			{Alias: "root", Type: jks.TrustedCertEntry, Created: created, Certificates: []*x509.Certificate{root.cert}},
+			{Alias: "server", Type: jks.PrivateKeyEntry, Created: created, Certificates: []*x509.Certificate{leaf.cert, root.cert}},
+		},
+	}
+
+	info := KeyStoreInfoFromStore(store)
+	if info.Type != "JKS" || info.Version != 2 || !info.IntegrityVerified || !info.Partial || info.Note != "stopped" {
+		t.Errorf("unexpected store %+v", info)
+	}
+	if len(info.Entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(info.Entries))
+	}
+
+	trusted := info.Entries[0]
+	if trusted.Alias != "root" || trusted.Type != "trustedCert" || trusted.Created != "2024-03-01T12:00:00Z" {
+		t.Errorf("unexpected entry %+v", trusted)
+	}
+	if len(trusted.Certificates) != 1 || trusted.Certificates[0].Type != "root" || trusted.Certificates[0].Source != SourceFile {
+		t.Errorf("unexpected certificates %+v", trusted.Certificates)
+	}
+
+	key := info.Entries[1]
+	if len(key.Certificates) != 2 {
+		t.Fatalf("expected 2 certificates, got %d", len(key.Certificates))
+	}
+	if key.Certificates[0].Type != "leaf" || key.Certificates[0].Subject != "CN=localhost" || key.Certificates[1].Type != "root" {
+		t.Errorf("unexpected chain %s (%s), %s", key.Certificates[0].Subject, key.Certificates[0].Type, key.Certificates[1].Type)
+	}
+}
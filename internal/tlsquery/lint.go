@@ -0,0 +1,244 @@
+package tlsquery
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Lint finding severities.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// LintFinding is a rule violated by a certificate. Citation names the
+// requirement the rule enforces.
+type LintFinding struct {
+	RuleID   string `json:"rule_id"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Citation string `json:"citation"`
+}
+
+// LintResult holds the findings for one certificate of a chain.
+type LintResult struct {
+	Type     string        `json:"type"`
+	Subject  string        `json:"subject"`
+	Findings []LintFinding `json:"findings"`
+}
+
+// LintReport holds the findings for a chain, with totals per severity.
+type LintReport struct {
+	Certificates []LintResult `json:"certificates"`
+	Errors       int          `json:"errors"`
+	Warnings     int          `json:"warnings"`
+}
+
+// lintRule checks a single requirement. check returns a description of the
+// violation, or "" when the certificate complies or the rule does not apply.
+type lintRule struct {
+	id       string
+	severity string
+	citation string
+	check    func(cert *x509.Certificate) string
+}
+
+// Subscriber certificates issued on or after this date are limited to 398
+// days of validity.
+var maxValidityEffective = time.Date(2020, 9, 1, 0, 0, 0, 0, time.UTC)
+
+var lintRules = []lintRule{
+	{"validity-too-long", SeverityError, "CA/B Forum BR 6.3.2", lintValidity},
+	{"weak-signature-hash", SeverityError, "CA/B Forum BR 7.1.3.2", lintSignatureHash},
+	{"rsa-key-too-small", SeverityError, "CA/B Forum BR 6.1.5", lintRSAKeySize},
+	{"missing-san", SeverityError, "CA/B Forum BR 7.1.2.7.12", lintMissingSAN},
+	{"cn-not-in-san", SeverityError, "CA/B Forum BR 7.1.4.3", lintCommonName},
+	{"ca-missing-cert-sign", SeverityError, "CA/B Forum BR 7.1.2.10.7", lintCAKeyUsage},
+	{"missing-aki", SeverityError, "RFC 5280 4.2.1.1", lintMissingAKI},
+	{"serial-negative", SeverityError, "RFC 5280 4.1.2.2", lintSerialNegative},
+	{"serial-too-long", SeverityError, "RFC 5280 4.1.2.2", lintSerialLength},
+	{"serial-low-entropy", SeverityWarning, "CA/B Forum BR 7.1", lintSerialEntropy},
+	{"wildcard-misuse", SeverityError, "CA/B Forum BR 1.6.1 (Wildcard Domain Name), RFC 6125 6.4.3", lintWildcards},
+}
+
+// LintChain checks every certificate of chain against the built-in rule
+// set. Subscriber rules apply to certificates that are neither CAs nor
+// self-signed.
+func LintChain(chain *ChainInfo) *LintReport {
+	report := &LintReport{Certificates: make([]LintResult, 0, len(chain.certs))}
+	for i, cert := range chain.certs {
+		result := LintResult{
+			Type:     chain.Certificates[i].Type,
+			Subject:  cert.Subject.String(),
+			Findings: []LintFinding{},
+		}
+		for _, rule := range lintRules {
+			message := rule.check(cert)
+			if message == "" {
+				continue
+			}
+			result.Findings = append(result.Findings, LintFinding{
+				RuleID:   rule.id,
+				Severity: rule.severity,
+				Message:  message,
+				Citation: rule.citation,
+			})
+			if rule.severity == SeverityError {
+				report.Errors++
+			} else {
+				report.Warnings++
+			}
+		}
+		report.Certificates = append(report.Certificates, result)
+	}
+	return report
+}
+
+// isSubscriber reports whether cert is a subscriber certificate. Roots may
+// lack basicConstraints (all v1 roots do), so self-signed certificates are
+// treated as CAs as well.
+func isSubscriber(cert *x509.Certificate) bool {
+	return !cert.IsCA && !isSelfSigned(cert)
+}
+
+func isSelfSigned(cert *x509.Certificate) bool {
+	return isSelfIssued(cert) &&
+		cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) == nil
+}
+
+func lintValidity(cert *x509.Certificate) string {
+	if !isSubscriber(cert) || cert.NotBefore.Before(maxValidityEffective) {
+		return ""
+	}
+	// The validity period includes both NotBefore and NotAfter.
+	validity := cert.NotAfter.Sub(cert.NotBefore) + time.Second
+	if validity > 398*24*time.Hour {
+		return fmt.Sprintf("validity period of %d days exceeds 398 days", days(validity))
+	}
+	return ""
+}
+
+func lintSignatureHash(cert *x509.Certificate) string {
+	// The signature on a root is not relied upon.
+	if isSelfIssued(cert) {
+		return ""
+	}
+	switch cert.SignatureAlgorithm {
+	case x509.MD2WithRSA, x509.MD5WithRSA, x509.SHA1WithRSA, x509.DSAWithSHA1, x509.ECDSAWithSHA1:
+		return fmt.Sprintf("signed with %s", cert.SignatureAlgorithm)
+	}
+	return ""
+}
+
+func lintRSAKeySize(cert *x509.Certificate) string {
+	if key, ok := cert.PublicKey.(*rsa.PublicKey); ok && key.N.BitLen() < 2048 {
+		return fmt.Sprintf("RSA key of %d bits is smaller than 2048 bits", key.N.BitLen())
+	}
+	return ""
+}
+
+func hasSAN(cert *x509.Certificate) bool {
+	return len(cert.DNSNames) > 0 || len(cert.IPAddresses) > 0
+}
+
+func lintMissingSAN(cert *x509.Certificate) string {
+	if isSubscriber(cert) && !hasSAN(cert) {
+		return "no DNS names or IP addresses in the subjectAltName extension"
+	}
+	return ""
+}
+
+func lintCommonName(cert *x509.Certificate) string {
+	cn := cert.Subject.CommonName
+	if !isSubscriber(cert) || cn == "" || !hasSAN(cert) {
+		// A certificate without SANs is reported by missing-san.
+		return ""
+	}
+	for _, name := range cert.DNSNames {
+		if strings.EqualFold(name, cn) {
+			return ""
+		}
+	}
+	for _, ip := range cert.IPAddresses {
+		if ip.String() == cn {
+			return ""
+		}
+	}
+	return fmt.Sprintf("common name %q is not in the subjectAltName extension", cn)
+}
+
+func lintCAKeyUsage(cert *x509.Certificate) string {
+	if cert.IsCA && cert.KeyUsage&x509.KeyUsageCertSign == 0 {
+		return "CA certificate without keyCertSign key usage"
+	}
+	return ""
+}
+
+func lintMissingAKI(cert *x509.Certificate) string {
+	if len(cert.AuthorityKeyId) == 0 && !bytes.Equal(cert.RawIssuer, cert.RawSubject) {
+		return "no authorityKeyIdentifier extension"
+	}
+	return ""
+}
+
+func lintSerialNegative(cert *x509.Certificate) string {
+	if cert.SerialNumber.Sign() < 0 {
+		return "serial number is negative"
+	}
+	return ""
+}
+
+// serialLength returns the length of the DER encoding of a non-negative
+// serial number, which has a leading zero byte when the high bit is set.
+func serialLength(cert *x509.Certificate) int {
+	b := cert.SerialNumber.Bytes()
+	if len(b) == 0 || b[0]&0x80 != 0 {
+		return len(b) + 1
+	}
+	return len(b)
+}
+
+func lintSerialLength(cert *x509.Certificate) string {
+	if cert.SerialNumber.Sign() >= 0 && serialLength(cert) > 20 {
+		return fmt.Sprintf("serial number is %d octets long, more than 20", serialLength(cert))
+	}
+	return ""
+}
+
+// lintSerialEntropy flags serials of fewer than 8 octets. Counting bits
+// would misfire on 64-bit random serials whose leading bits happen to be
+// zero.
+func lintSerialEntropy(cert *x509.Certificate) string {
+	if n := len(cert.SerialNumber.Bytes()); cert.SerialNumber.Sign() > 0 && n < 8 {
+		return fmt.Sprintf("serial number of %d octets cannot hold 64 bits of random output", n)
+	}
+	return ""
+}
+
+func lintWildcards(cert *x509.Certificate) string {
+	if !isSubscriber(cert) {
+		return ""
+	}
+	names := append([]string{cert.Subject.CommonName}, cert.DNSNames...)
+	seen := map[string]bool{}
+
+	var problems []string
+	for _, name := range names {
+		if !strings.Contains(name, "*") || seen[name] {
+			continue
+		}
+		seen[name] = true
+		labels := strings.Split(name, ".")
+		switch {
+		case labels[0] != "*" || strings.Contains(strings.Join(labels[1:], "."), "*"):
+			problems = append(problems, fmt.Sprintf("%q: only the whole leftmost label may be a wildcard", name))
+		case len(labels) < 3:
+			problems = append(problems, fmt.Sprintf("%q: wildcard directly below a top-level domain", name))
+		}
+	}
+	return strings.Join(problems, "; ")
+}
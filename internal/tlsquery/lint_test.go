@@ -0,0 +1,189 @@
+package tlsquery
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+// subscriberCert returns a compliant subscriber certificate for the rule
+// tests to break.
+func subscriberCert() *x509.Certificate {
+	notBefore := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	serial, _ := new(big.Int).SetString("7f3a9c1e5b2d4f6081a3c5e7f9b1d3e5", 16)
+	return &x509.Certificate{
+		SerialNumber:       serial,
+		Subject:            pkix.Name{CommonName: "www.example.com"},
+		RawSubject:         []byte("subject"),
+		RawIssuer:          []byte("issuer"),
+		NotBefore:          notBefore,
+		NotAfter:           notBefore.Add(90 * 24 * time.Hour),
+		SignatureAlgorithm: x509.ECDSAWithSHA256,
+		DNSNames:           []string{"www.example.com", "example.com"},
+		AuthorityKeyId:     []byte{1, 2, 3},
+	}
+}
+
+func TestLintRules(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(cert *x509.Certificate)
+		want   string
+	}{
+		{"compliant", func(cert *x509.Certificate) {}, ""},
+		{"validity", func(cert *x509.Certificate) {
+			cert.NotAfter = cert.NotBefore.Add(399 * 24 * time.Hour)
+		}, "validity-too-long"},
+		{"validity before September 2020", func(cert *x509.Certificate) {
+			cert.NotBefore = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+			cert.NotAfter = cert.NotBefore.Add(825 * 24 * time.Hour)
+		}, ""},
+		{"SHA-1", func(cert *x509.Certificate) {
+			cert.SignatureAlgorithm = x509.SHA1WithRSA
+		}, "weak-signature-hash"},
+		{"RSA 1024", func(cert *x509.Certificate) {
+			cert.PublicKey = &rsa.PublicKey{N: new(big.Int).Lsh(big.NewInt(1), 1023), E: 65537}
+		}, "rsa-key-too-small"},
+		{"missing SAN", func(cert *x509.Certificate) {
+			cert.DNSNames = nil
+		}, "missing-san"},
+		{"CN not in SAN", func(cert *x509.Certificate) {
+			cert.DNSNames = []string{"example.com"}
+		}, "cn-not-in-san"},
+		{"CN is an IP address", func(cert *x509.Certificate) {
+			cert.Subject.CommonName = "192.0.2.1"
+			cert.IPAddresses = []net.IP{net.ParseIP("192.0.2.1")}
+		}, ""},
+		{"CA without keyCertSign", func(cert *x509.Certificate) {
+			cert.IsCA = true
+			cert.DNSNames = nil
+			cert.KeyUsage = x509.KeyUsageCRLSign
+		}, "ca-missing-cert-sign"},
+		{"missing AKI", func(cert *x509.Certificate) {
+			cert.AuthorityKeyId = nil
+		}, "missing-aki"},
+		{"negative serial", func(cert *x509.Certificate) {
+			cert.SerialNumber = big.NewInt(-1)
+		}, "serial-negative"},
+		{"serial too long", func(cert *x509.Certificate) {
+			cert.SerialNumber = new(big.Int).Lsh(big.NewInt(1), 159)
+		}, "serial-too-long"},
+		{"serial with 20 octets", func(cert *x509.Certificate) {
+			cert.SerialNumber = new(big.Int).Lsh(big.NewInt(1), 158)
+		}, ""},
+		{"short serial", func(cert *x509.Certificate) {
+			cert.SerialNumber = big.NewInt(1000)
+		}, "serial-low-entropy"},
+		{"64-bit serial with high bit clear", func(cert *x509.Certificate) {
+			cert.SerialNumber = new(big.Int).SetUint64(0x7f3a9c1e5b2d4f60)
+		}, ""},
+		{"64-bit serial with leading zero bits", func(cert *x509.Certificate) {
+			cert.SerialNumber = new(big.Int).SetUint64(0x013a9c1e5b2d4f60)
+		}, ""},
+		{"partial wildcard", func(cert *x509.Certificate) {
+			cert.DNSNames = append(cert.DNSNames, "w*.example.com")
+		}, "wildcard-misuse"},
+		{"wildcard not leftmost", func(cert *x509.Certificate) {
+			cert.DNSNames = append(cert.DNSNames, "www.*.example.com")
+		}, "wildcard-misuse"},
+		{"wildcard on TLD", func(cert *x509.Certificate) {
+			cert.DNSNames = append(cert.DNSNames, "*.com")
+		}, "wildcard-misuse"},
+		{"valid wildcard", func(cert *x509.Certificate) {
+			cert.DNSNames = append(cert.DNSNames, "*.example.com")
+		}, ""},
+		{"CA with wildcard name", func(cert *x509.Certificate) {
+			cert.IsCA = true
+			cert.KeyUsage = x509.KeyUsageCertSign
+			cert.Subject.CommonName = "*.example"
+			cert.DNSNames = nil
+		}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert := subscriberCert()
+			tt.modify(cert)
+
+			var got []string
+			for _, rule := range lintRules {
+				if message := rule.check(cert); message != "" {
+					got = append(got, rule.id)
+				}
+			}
+			if tt.want == "" && len(got) > 0 {
+				t.Errorf("expected no findings, got %v", got)
+			}
+			if tt.want != "" && (len(got) != 1 || got[0] != tt.want) {
+				t.Errorf("expected %s, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// testV1RootPEM is a self-signed v1 certificate without extensions, made
+// with openssl x509 -req -signkey, valid for 20 years.
+const testV1RootPEM = `-----BEGIN CERTIFICATE-----
+MIIBKDCBzwIUMy6E6pPpJCA4XkKI4/xbIr21jfAwCgYIKoZIzj0EAwIwFzEVMBMG
+A1UEAwwMVGVzdCBWMSBSb290MB4XDTI2MTAxNzA0MjI1MFoXDTQ2MTAxMjA0MjI1
+MFowFzEVMBMGA1UEAwwMVGVzdCBWMSBSb290MFkwEwYHKoZIzj0CAQYIKoZIzj0D
+AQcDQgAEEOBJQlbXEM2UVmSYufogV3JRK/QHA/nDepyy24by9eDzXGwWqPSxbSdc
+YWoOKElUk6+SnKUM6kBBQFLlGLhkIjAKBggqhkjOPQQDAgNIADBFAiEA51nLjLLB
+HyQ8W33wUAe1BL6CbwGGqDGPqBmjo5wt2eACIFfTQI8mMQOYd7k4lstvVW/LI5sq
+9LGHlQLW+qw5QhI3
+-----END CERTIFICATE-----
+`
+
+func TestLintChain_V1Root(t *testing.T) {
+	chain, err := ParsePEM([]byte(testV1RootPEM))
+	if err != nil {
+		t.Fatalf("ParsePEM failed: %v", err)
+	}
+	if chain.certs[0].Version != 1 || chain.certs[0].BasicConstraintsValid {
+		t.Fatal("expected a v1 certificate without basicConstraints")
+	}
+
+	report := LintChain(chain)
+	if findings := report.Certificates[0].Findings; len(findings) != 0 {
+		t.Errorf("expected no subscriber findings for a v1 root, got %+v", findings)
+	}
+}
+
+func TestLintChain(t *testing.T) {
+	root := newTestCert(t, caTemplate("Test Root"), nil)
+	template := leafTemplate("localhost")
+	template.NotAfter = template.NotBefore.Add(400 * 24 * time.Hour)
+	leaf := newTestCert(t, template, root)
+
+	report := LintChain(chainOf(leaf, root))
+	if len(report.Certificates) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(report.Certificates))
+	}
+
+	result := report.Certificates[0]
+	if result.Type != "leaf" || result.Subject != "CN=localhost" {
+		t.Errorf("unexpected result %s (%s)", result.Subject, result.Type)
+	}
+	var found *LintFinding
+	for i := range result.Findings {
+		if result.Findings[i].RuleID == "validity-too-long" {
+			found = &result.Findings[i]
+		}
+	}
+	if found == nil {
+		t.Fatalf("expected validity-too-long finding, got %+v", result.Findings)
+	}
+	if found.Severity != SeverityError || found.Citation == "" || found.Message == "" {
+		t.Errorf("incomplete finding %+v", found)
+	}
+	if report.Errors < 1 {
+		t.Errorf("expected errors to be counted, got %d", report.Errors)
+	}
+	if report.Errors+report.Warnings != len(result.Findings)+len(report.Certificates[1].Findings) {
+		t.Errorf("totals %d/%d do not match the findings", report.Errors, report.Warnings)
+	}
+}
@@ -0,0 +1,155 @@
+package tlsquery
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// maxRevocationResponseSize bounds OCSP responses and CRLs read from the network.
+const maxRevocationResponseSize = 10 << 20
+
+// OCSPResult holds the revocation status of a certificate as reported by its
+// OCSP responder.
+type OCSPResult struct {
+	Responder        string `json:"responder"`
+	Status           string `json:"status,omitempty"`
+	RevokedAt        string `json:"revoked_at,omitempty"`
+	RevocationReason string `json:"revocation_reason,omitempty"`
+	ThisUpdate       string `json:"this_update,omitempty"`
+	NextUpdate       string `json:"next_update,omitempty"`
+	Error            string `json:"error,omitempty"`
+}
+
+// revocationReasons are the CRLReason names of RFC 5280 section 5.3.1.
+var revocationReasons = map[int]string{
+	ocsp.Unspecified:          "unspecified",
+	ocsp.KeyCompromise:        "keyCompromise",
+	ocsp.CACompromise:         "cACompromise",
+	ocsp.AffiliationChanged:   "affiliationChanged",
+	ocsp.Superseded:           "superseded",
+	ocsp.CessationOfOperation: "cessationOfOperation",
+	ocsp.CertificateHold:      "certificateHold",
+	ocsp.RemoveFromCRL:        "removeFromCRL",
+	ocsp.PrivilegeWithdrawn:   "privilegeWithdrawn",
+	ocsp.AACompromise:         "aACompromise",
+}
+
+func revocationReason(code int) string {
+	if name, ok := revocationReasons[code]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown (%d)", code)
+}
+
+// CheckOCSP queries the OCSP responder of every certificate in chain that
+// names one and stores the result in its OCSP field. The issuer is taken from
+// the chain; certificates without an issuer in the chain are reported as
+// errors. A nil client uses http.DefaultClient.
+func CheckOCSP(ctx context.Context, chain *ChainInfo, client *http.Client) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	for i, cert := range chain.certs {
+		if len(cert.OCSPServer) == 0 {
+			continue
+		}
+		result := &OCSPResult{Responder: cert.OCSPServer[0]}
+		chain.Certificates[i].OCSP = result
+
+		issuer := findIssuer(cert, chain.certs)
+		if issuer == nil {
+			result.Error = "issuer not found in chain"
+			continue
+		}
+		resp, err := queryOCSP(ctx, client, result.Responder, cert, issuer)
+		if err != nil {
+			result.Error = err.Error()
+			continue
+		}
+		fillOCSPResult(result, resp)
+	}
+}
+
+// findIssuer returns the certificate in candidates that signed cert, or nil.
+// Self-signed certificates have no issuer.
+func findIssuer(cert *x509.Certificate, candidates []*x509.Certificate) *x509.Certificate {
+	if bytes.Equal(cert.RawIssuer, cert.RawSubject) && cert.CheckSignatureFrom(cert) == nil {
+		return nil
+	}
+	for _, candidate := range candidates {
+		if candidate != cert && bytes.Equal(cert.RawIssuer, candidate.RawSubject) && cert.CheckSignatureFrom(candidate) == nil {
+			return candidate
+		}
+	}
+	return nil
+}
+
+// queryOCSP POSTs an OCSP request for cert to responder and returns the
+// parsed and signature-checked response.
+func queryOCSP(ctx context.Context, client *http.Client, responder string, cert, issuer *x509.Certificate) (*ocsp.Response, error) {
+	reqBody, err := ocsp.CreateRequest(cert, issuer, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OCSP request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, responder, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("invalid OCSP responder URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/ocsp-request")
+	req.Header.Set("Accept", "application/ocsp-response")
+
+	httpResp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("OCSP request failed: %w", err)
+	}
+	defer httpResp.Body.Close()
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OCSP responder returned HTTP %s", httpResp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(httpResp.Body, maxRevocationResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OCSP response: %w", err)
+	}
+
+	resp, err := ocsp.ParseResponseForCert(body, cert, issuer)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OCSP response: %w", err)
+	}
+	return resp, nil
+}
+
+func fillOCSPResult(result *OCSPResult, resp *ocsp.Response) {
+	result.Status, result.RevokedAt, result.RevocationReason = ocspStatus(resp)
+	result.ThisUpdate = formatTime(resp.ThisUpdate)
+	result.NextUpdate = formatTime(resp.NextUpdate)
+}
+
+// ocspStatus returns the certificate status of resp and, for revoked
+// certificates, the revocation time and reason.
+func ocspStatus(resp *ocsp.Response) (status, revokedAt, reason string) {
+	switch resp.Status {
+	case ocsp.Good:
+		return "good", "", ""
+	case ocsp.Revoked:
+		return "revoked", formatTime(resp.RevokedAt), revocationReason(resp.RevocationReason)
+	default:
+		return "unknown", "", ""
+	}
+}
+
+// formatTime formats t like the certificate dates, or returns "" for the
+// zero time.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
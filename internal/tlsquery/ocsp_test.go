@@ -0,0 +1,176 @@
+package tlsquery
+
+import (
+	"context"
+	"crypto/x509"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// startOCSPResponder serves OCSP responses signed by issuer. The status of
+// each certificate is looked up by serial number in statuses.
+func startOCSPResponder(t *testing.T, issuer *testCert, statuses map[string]ocsp.Response) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil || r.Header.Get("Content-Type") != "application/ocsp-request" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		req, err := ocsp.ParseRequest(body)
+		if err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		template, ok := statuses[req.SerialNumber.String()]
+		if !ok {
+			w.Write(ocsp.UnauthorizedErrorResponse)
+			return
+		}
+		template.SerialNumber = req.SerialNumber
+		resp, err := ocsp.CreateResponse(issuer.cert, issuer.cert, template, issuer.key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/ocsp-response")
+		w.Write(resp)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCheckOCSP(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	root := newTestCert(t, caTemplate("Test Root"), nil)
+
+	statuses := map[string]ocsp.Response{}
+	responder := startOCSPResponder(t, root, statuses)
+
+	newLeaf := func(serial int64, ocspServer string) *testCert {
+		tmpl := leafTemplate("test.example.com")
+		tmpl.SerialNumber = big.NewInt(serial)
+		tmpl.OCSPServer = []string{ocspServer}
+		return newTestCert(t, tmpl, root)
+	}
+
+	good := newLeaf(1, responder.URL)
+	statuses["1"] = ocsp.Response{Status: ocsp.Good, ThisUpdate: now, NextUpdate: now.Add(time.Hour)}
+	revoked := newLeaf(2, responder.URL)
+	statuses["2"] = ocsp.Response{
+		Status:           ocsp.Revoked,
+		ThisUpdate:       now,
+		RevokedAt:        now.Add(-time.Hour),
+		RevocationReason: ocsp.KeyCompromise,
+	}
+	unknown := newLeaf(3, responder.URL)
+	statuses["3"] = ocsp.Response{Status: ocsp.Unknown, ThisUpdate: now}
+	unauthorized := newLeaf(4, responder.URL)
+	unreachable := newLeaf(5, "http://127.0.0.1:1/")
+
+	tests := []struct {
+		name      string
+		certs     []*x509.Certificate
+		want      OCSPResult
+		wantError bool
+	}{
+		{
+			name:  "good",
+			certs: []*x509.Certificate{good.cert, root.cert},
+			want: OCSPResult{
+				Status:     "good",
+				ThisUpdate: formatTime(now),
+				NextUpdate: formatTime(now.Add(time.Hour)),
+			},
+		},
+		{
+			name:  "revoked",
+			certs: []*x509.Certificate{revoked.cert, root.cert},
+			want: OCSPResult{
+				Status:           "revoked",
+				RevokedAt:        formatTime(now.Add(-time.Hour)),
+				RevocationReason: "keyCompromise",
+				ThisUpdate:       formatTime(now),
+			},
+		},
+		{
+			name:  "unknown",
+			certs: []*x509.Certificate{unknown.cert, root.cert},
+			want:  OCSPResult{Status: "unknown", ThisUpdate: formatTime(now)},
+		},
+		{
+			name:      "responder error",
+			certs:     []*x509.Certificate{unauthorized.cert, root.cert},
+			wantError: true,
+		},
+		{
+			name:      "responder unreachable",
+			certs:     []*x509.Certificate{unreachable.cert, root.cert},
+			wantError: true,
+		},
+		{
+			name:      "issuer missing",
+			certs:     []*x509.Certificate{good.cert},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := &ChainInfo{certs: tt.certs}
+			for _, cert := range tt.certs {
+				chain.Certificates = append(chain.Certificates, CertInfoFromCert(cert))
+			}
+
+			CheckOCSP(context.Background(), chain, nil)
+
+			result := chain.Certificates[0].OCSP
+			if result == nil {
+				t.Fatal("expected an OCSP result for the leaf")
+			}
+			if tt.wantError {
+				if result.Error == "" {
+					t.Errorf("expected an error, got %+v", result)
+				}
+				return
+			}
+			tt.want.Responder = tt.certs[0].OCSPServer[0]
+			if *result != tt.want {
+				t.Errorf("got %+v, want %+v", *result, tt.want)
+			}
+			if len(tt.certs) > 1 && chain.Certificates[1].OCSP != nil {
+				t.Error("root without OCSP server should not be checked")
+			}
+		})
+	}
+}
+
+func TestCheckOCSP_WrongSigner(t *testing.T) {
+	root := newTestCert(t, caTemplate("Test Root"), nil)
+	other := newTestCert(t, caTemplate("Other Root"), nil)
+	responder := startOCSPResponder(t, other, map[string]ocsp.Response{
+		"1": {Status: ocsp.Good, ThisUpdate: time.Now()},
+	})
+
+	tmpl := leafTemplate("test.example.com")
+	tmpl.SerialNumber = big.NewInt(1)
+	tmpl.OCSPServer = []string{responder.URL}
+	leaf := newTestCert(t, tmpl, root)
+
+	chain, err := ParsePEM([]byte(encodePEM(leaf.cert.Raw) + encodePEM(root.cert.Raw)))
+	if err != nil {
+		t.Fatalf("ParsePEM failed: %v", err)
+	}
+	CheckOCSP(context.Background(), chain, nil)
+
+	if result := chain.Certificates[0].OCSP; result == nil || result.Error == "" || result.Status != "" {
+		t.Errorf("expected signature error, got %+v", result)
+	}
+}
@@ -0,0 +1,181 @@
+package tlsquery
+
+import (
+	"bytes"
+	"crypto/x509"
+	"fmt"
+)
+
+// Chain issue types.
+const (
+	IssueWrongOrder  = "wrong_order"
+	IssueDuplicate   = "duplicate"
+	IssueExtra       = "extra"
+	IssueMissingLink = "missing_link"
+)
+
+// ChainAnalysis describes how the certificates of a chain link together.
+// Path lists the positions (0-based, in input order) of the certificates on
+// the path from the leaf towards the root. It covers the certificates as
+// served or read; issuers fetched by CompleteChain are not included.
+type ChainAnalysis struct {
+	Ordered bool         `json:"ordered"`
+	Path    []int        `json:"path"`
+	Issues  []ChainIssue `json:"issues,omitempty"`
+}
+
+// ChainIssue is a problem found by AnalyzeChain. Index is the position of
+// the certificate concerned.
+type ChainIssue struct {
+	Type    string `json:"type"`
+	Index   int    `json:"index"`
+	Message string `json:"message"`
+}
+
+// AnalyzeChain links each certificate to its issuer by issuer/subject name
+// and authority/subject key ID, starting from the leaf, and reports
+// certificates that are out of order, duplicated, unrelated to the path, or
+// a gap between the path and further certificates in the bundle.
+func AnalyzeChain(certs []*x509.Certificate) *ChainAnalysis {
+	analysis := &ChainAnalysis{Ordered: true}
+	if len(certs) == 0 {
+		return analysis
+	}
+
+	// Duplicates are reported and otherwise ignored.
+	var unique []int
+	for i, cert := range certs {
+		duplicate := false
+		for _, j := range unique {
+			if bytes.Equal(cert.Raw, certs[j].Raw) {
+				analysis.Issues = append(analysis.Issues, ChainIssue{
+					Type:    IssueDuplicate,
+					Index:   i,
+					Message: fmt.Sprintf("%s is a duplicate of certificate %d", cert.Subject, j),
+				})
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			unique = append(unique, i)
+		}
+	}
+
+	leaf := findLeaf(certs, unique)
+	used := map[int]bool{leaf: true}
+	analysis.Path = []int{leaf}
+	for current := leaf; !isSelfIssued(certs[current]); {
+		next := -1
+		for _, j := range unique {
+			if !used[j] && issuedBy(certs[current], certs[j]) {
+				next = j
+				break
+			}
+		}
+		if next < 0 {
+			break
+		}
+		used[next] = true
+		analysis.Path = append(analysis.Path, next)
+		current = next
+	}
+
+	for i, index := range analysis.Path {
+		if index != i {
+			analysis.Ordered = false
+			analysis.Issues = append(analysis.Issues, ChainIssue{
+				Type:    IssueWrongOrder,
+				Index:   index,
+				Message: fmt.Sprintf("%s is at position %d but belongs at position %d", certs[index].Subject, index, i),
+			})
+		}
+	}
+
+	var extras []int
+	for _, j := range unique {
+		if !used[j] {
+			extras = append(extras, j)
+		}
+	}
+
+	top := analysis.Path[len(analysis.Path)-1]
+	if !isSelfIssued(certs[top]) && len(extras) > 0 {
+		analysis.Issues = append(analysis.Issues, ChainIssue{
+			Type:    IssueMissingLink,
+			Index:   top,
+			Message: fmt.Sprintf("issuer %s of %s is not in the chain", certs[top].Issuer, certs[top].Subject),
+		})
+	}
+	for _, j := range extras {
+		analysis.Issues = append(analysis.Issues, ChainIssue{
+			Type:    IssueExtra,
+			Index:   j,
+			Message: fmt.Sprintf("%s is not part of the chain of the leaf", certs[j].Subject),
+		})
+	}
+
+	return analysis
+}
+
+// findLeaf returns the certificate that issued none of the others,
+// preferring end-entity certificates and earlier positions.
+func findLeaf(certs []*x509.Certificate, candidates []int) int {
+	leaf := -1
+	for _, i := range candidates {
+		isIssuer := false
+		for _, j := range candidates {
+			if i != j && issuedBy(certs[j], certs[i]) {
+				isIssuer = true
+				break
+			}
+		}
+		if isIssuer {
+			continue
+		}
+		if leaf < 0 || (certs[leaf].IsCA && !certs[i].IsCA) {
+			leaf = i
+		}
+	}
+	if leaf < 0 {
+		return candidates[0]
+	}
+	return leaf
+}
+
+// issuedBy reports whether issuer's subject matches cert's issuer name and,
+// when both are present, its subject key ID matches cert's authority key ID.
+func issuedBy(cert, issuer *x509.Certificate) bool {
+	if !bytes.Equal(cert.RawIssuer, issuer.RawSubject) {
+		return false
+	}
+	if len(cert.AuthorityKeyId) > 0 && len(issuer.SubjectKeyId) > 0 {
+		return bytes.Equal(cert.AuthorityKeyId, issuer.SubjectKeyId)
+	}
+	return true
+}
+
+func isSelfIssued(cert *x509.Certificate) bool {
+	return issuedBy(cert, cert)
+}
+
+// OrderChain returns a copy of chain holding only the certificates on the
+// leaf-to-root path, in that order. Duplicates and unrelated certificates
+// are dropped.
+func OrderChain(chain *ChainInfo) *ChainInfo {
+	analysis := chain.Analysis
+	if analysis == nil {
+		analysis = AnalyzeChain(chain.certs)
+	}
+
+	ordered := &ChainInfo{SkippedBlocks: chain.SkippedBlocks}
+	for i, index := range analysis.Path {
+		cert := chain.certs[index]
+		info := chain.Certificates[index]
+		info.Type = certType(i, cert)
+		ordered.Certificates = append(ordered.Certificates, info)
+		ordered.certs = append(ordered.certs, cert)
+	}
+	ordered.Analysis = AnalyzeChain(ordered.certs)
+	return ordered
+}
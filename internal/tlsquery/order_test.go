@@ -0,0 +1,140 @@
+package tlsquery
+
+import (
+	"crypto/x509"
+	"reflect"
+	"testing"
+)
+
+func TestAnalyzeChain(t *testing.T) {
+	root := newTestCert(t, caTemplate("Test Root"), nil)
+	intermediate := newTestCert(t, caTemplate("Test Intermediate"), root)
+	leaf := newTestCert(t, leafTemplate("localhost"), intermediate)
+	other := newTestCert(t, caTemplate("Other Root"), nil)
+	// Same subject as the intermediate, but a different key.
+	impostor := newTestCert(t, caTemplate("Test Intermediate"), other)
+
+	tests := []struct {
+		name    string
+		certs   []*testCert
+		ordered bool
+		path    []int
+		issues  []ChainIssue
+	}{
+		{
+			name:    "ordered",
+			certs:   []*testCert{leaf, intermediate, root},
+			ordered: true,
+			path:    []int{0, 1, 2},
+		},
+		{
+			name:    "root omitted",
+			certs:   []*testCert{leaf, intermediate},
+			ordered: true,
+			path:    []int{0, 1},
+		},
+		{
+			name:    "reversed",
+			certs:   []*testCert{root, intermediate, leaf},
+			ordered: false,
+			path:    []int{2, 1, 0},
+			issues: []ChainIssue{
+				{Type: IssueWrongOrder, Index: 2},
+				{Type: IssueWrongOrder, Index: 0},
+			},
+		},
+		{
+			name:    "duplicate",
+			certs:   []*testCert{leaf, intermediate, intermediate},
+			ordered: true,
+			path:    []int{0, 1},
+			issues:  []ChainIssue{{Type: IssueDuplicate, Index: 2}},
+		},
+		{
+			name:    "extra",
+			certs:   []*testCert{leaf, intermediate, root, other},
+			ordered: true,
+			path:    []int{0, 1, 2},
+			issues:  []ChainIssue{{Type: IssueExtra, Index: 3}},
+		},
+		{
+			name:    "missing link",
+			certs:   []*testCert{leaf, root},
+			ordered: true,
+			path:    []int{0},
+			issues: []ChainIssue{
+				{Type: IssueMissingLink, Index: 0},
+				{Type: IssueExtra, Index: 1},
+			},
+		},
+		{
+			name:    "key ID mismatch",
+			certs:   []*testCert{leaf, impostor, intermediate},
+			ordered: false,
+			path:    []int{0, 2},
+			issues: []ChainIssue{
+				{Type: IssueWrongOrder, Index: 2},
+				{Type: IssueMissingLink, Index: 2},
+				{Type: IssueExtra, Index: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var certs []*x509.Certificate
+			for _, c := range tt.certs {
+				certs = append(certs, c.cert)
+			}
+
+			analysis := AnalyzeChain(certs)
+			if analysis.Ordered != tt.ordered {
+				t.Errorf("expected ordered %t, got %t", tt.ordered, analysis.Ordered)
+			}
+			if !reflect.DeepEqual(analysis.Path, tt.path) {
+				t.Errorf("expected path %v, got %v", tt.path, analysis.Path)
+			}
+			if len(analysis.Issues) != len(tt.issues) {
+				t.Fatalf("expected %d issues, got %+v", len(tt.issues), analysis.Issues)
+			}
+			for i, want := range tt.issues {
+				got := analysis.Issues[i]
+				if got.Type != want.Type || got.Index != want.Index {
+					t.Errorf("issue %d: expected %s at %d, got %s at %d", i, want.Type, want.Index, got.Type, got.Index)
+				}
+				if got.Message == "" {
+					t.Errorf("issue %d: expected a message", i)
+				}
+			}
+		})
+	}
+}
+
+func TestOrderChain(t *testing.T) {
+	root := newTestCert(t, caTemplate("Test Root"), nil)
+	intermediate := newTestCert(t, caTemplate("Test Intermediate"), root)
+	leaf := newTestCert(t, leafTemplate("localhost"), intermediate)
+	other := newTestCert(t, caTemplate("Other Root"), nil)
+
+	chain := chainOf(root, other, intermediate, leaf, intermediate)
+	chain.Analysis = AnalyzeChain(chain.certs)
+
+	ordered := OrderChain(chain)
+	want := []struct{ subject, certType string }{
+		{"CN=localhost", "leaf"},
+		{"CN=Test Intermediate", "intermediate"},
+		{"CN=Test Root", "root"},
+	}
+	if len(ordered.Certificates) != len(want) {
+		t.Fatalf("expected %d certificates, got %d", len(want), len(ordered.Certificates))
+	}
+	for i, w := range want {
+		cert := ordered.Certificates[i]
+		if cert.Subject != w.subject || cert.Type != w.certType {
+			t.Errorf("certificate %d: expected %s (%s), got %s (%s)", i, w.subject, w.certType, cert.Subject, cert.Type)
+		}
+	}
+	if !ordered.Analysis.Ordered || len(ordered.Analysis.Issues) != 0 {
+		t.Errorf("expected reordered chain to have no issues, got %+v", ordered.Analysis)
+	}
+}
@@ -2,11 +2,20 @@ package tlsquery
 
 import (
 	"crypto/x509"
+	"encoding/asn1"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"os"
 )
 
+// SkippedBlock is a PEM block that ParsePEM ignored because it does not hold
+// certificates. Block is its 1-based position in the input.
+type SkippedBlock struct {
+	Block int    `json:"block"`
+	Type  string `json:"type"`
+}
+
 // ParsePEMFile reads a PEM file and returns certificate information for all certificates found.
 func ParsePEMFile(path string) (*ChainInfo, error) {
 	data, err := os.ReadFile(path)
@@ -17,38 +26,90 @@ func ParsePEMFile(path string) (*ChainInfo, error) {
 	return ParsePEM(data)
 }
 
-// ParsePEM parses PEM-encoded certificate data and returns certificate information.
+// ParsePEM parses certificate data and returns certificate information. PEM
+// CERTIFICATE, TRUSTED CERTIFICATE and PKCS7 blocks are read; other blocks
+// are reported in SkippedBlocks. Data without PEM blocks is parsed as DER
+// certificates or a DER PKCS#7 bundle.
 func ParsePEM(data []byte) (*ChainInfo, error) {
 	var certs []*x509.Certificate
+	var skipped []SkippedBlock
 
-	for {
-		block, rest := pem.Decode(data)
-		if block == nil {
-			break
+	block, rest := pem.Decode(data)
+	if block == nil {
+		var err error
+		if certs, err = parseDER(data); err != nil {
+			return nil, err
 		}
-
-		if block.Type == "CERTIFICATE" {
+	}
+	for n := 1; block != nil; n++ {
+		switch block.Type {
+		case "CERTIFICATE":
 			cert, err := x509.ParseCertificate(block.Bytes)
 			if err != nil {
 				return nil, fmt.Errorf("failed to parse certificate: %w", err)
 			}
 			certs = append(certs, cert)
+		case "TRUSTED CERTIFICATE":
+			cert, err := parseTrustedCertificate(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse trusted certificate: %w", err)
+			}
+			certs = append(certs, cert)
+		case "PKCS7":
+			bundle, err := parsePKCS7Certificates(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse PKCS#7 bundle: %w", err)
+			}
+			certs = append(certs, bundle...)
+		default:
+			skipped = append(skipped, SkippedBlock{Block: n, Type: block.Type})
 		}
 
-		data = rest
+		block, rest = pem.Decode(rest)
 	}
 
 	if len(certs) == 0 {
-		return nil, fmt.Errorf("no certificates found in PEM data")
+		return nil, fmt.Errorf("no certificates found")
 	}
 
 	chain := &ChainInfo{
-		Certificates: make([]CertInfo, 0, len(certs)),
+		Certificates:  make([]CertInfo, 0, len(certs)),
+		SkippedBlocks: skipped,
+		certs:         certs,
 	}
 
 	for _, cert := range certs {
-		chain.Certificates = append(chain.Certificates, CertInfoFromCert(cert))
+		info := CertInfoFromCert(cert)
+		info.Source = SourceFile
+		chain.Certificates = append(chain.Certificates, info)
 	}
+	chain.Analysis = AnalyzeChain(certs)
 
 	return chain, nil
 }
+
+// parseDER parses binary data holding one or more concatenated DER
+// certificates, or a PKCS#7 bundle.
+func parseDER(data []byte) ([]*x509.Certificate, error) {
+	if len(data) == 0 {
+		return nil, errors.New("no certificates found")
+	}
+	certs, err := x509.ParseCertificates(data)
+	if err == nil {
+		return certs, nil
+	}
+	if bundle, p7Err := parsePKCS7Certificates(data); p7Err == nil {
+		return bundle, nil
+	}
+	return nil, fmt.Errorf("no certificates found: data is neither PEM nor a DER certificate or PKCS#7 bundle (%v)", err)
+}
+
+// parseTrustedCertificate parses an OpenSSL TRUSTED CERTIFICATE block: a DER
+// certificate followed by OpenSSL's trust settings, which are ignored.
+func parseTrustedCertificate(der []byte) (*x509.Certificate, error) {
+	var raw asn1.RawValue
+	if _, err := asn1.Unmarshal(der, &raw); err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(raw.FullBytes)
+}
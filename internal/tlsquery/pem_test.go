@@ -1,8 +1,10 @@
 package tlsquery
 
 import (
+	"encoding/pem"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -114,6 +116,81 @@ func TestParsePEM(t *testing.T) {
 	}
 }
 
+func TestParsePEM_Formats(t *testing.T) {
+	root := newTestCert(t, caTemplate("Test Root"), nil)
+	leaf := newTestCert(t, leafTemplate("localhost"), root)
+	pemBlock := func(blockType string, der []byte) string {
+		return string(pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}))
+	}
+	// OpenSSL appends its trust settings after the certificate.
+	trusted := append(append([]byte{}, root.cert.Raw...), 0x30, 0x00)
+
+	tests := []struct {
+		name        string
+		data        []byte
+		wantSubject []string
+		wantSkipped []SkippedBlock
+	}{
+		{
+			name:        "DER certificate",
+			data:        leaf.cert.Raw,
+			wantSubject: []string{"CN=localhost"},
+		},
+		{
+			name:        "concatenated DER certificates",
+			data:        append(append([]byte{}, leaf.cert.Raw...), root.cert.Raw...),
+			wantSubject: []string{"CN=localhost", "CN=Test Root"},
+		},
+		{
+			name:        "DER PKCS#7",
+			data:        newPKCS7(t, leaf, root),
+			wantSubject: []string{"CN=localhost", "CN=Test Root"},
+		},
+		{
+			name:        "PEM PKCS#7",
+			data:        []byte(pemBlock("PKCS7", newPKCS7(t, leaf, root))),
+			wantSubject: []string{"CN=localhost", "CN=Test Root"},
+		},
+		{
+			name:        "trusted certificate",
+			data:        []byte(pemBlock("TRUSTED CERTIFICATE", trusted)),
+			wantSubject: []string{"CN=Test Root"},
+		},
+		{
+			name: "skipped blocks",
+			data: []byte(pemBlock("PRIVATE KEY", []byte("key")) +
+				pemBlock("CERTIFICATE", leaf.cert.Raw) +
+				pemBlock("X509 CRL", []byte("crl"))),
+			wantSubject: []string{"CN=localhost"},
+			wantSkipped: []SkippedBlock{{Block: 1, Type: "PRIVATE KEY"}, {Block: 3, Type: "X509 CRL"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain, err := ParsePEM(tt.data)
+			if err != nil {
+				t.Fatalf("ParsePEM() unexpected error: %v", err)
+			}
+			if len(chain.Certificates) != len(tt.wantSubject) {
+				t.Fatalf("ParsePEM() got %d certificates, want %d", len(chain.Certificates), len(tt.wantSubject))
+			}
+			for i, subject := range tt.wantSubject {
+				if chain.Certificates[i].Subject != subject {
+					t.Errorf("certificate %d: Subject = %q, want %q", i, chain.Certificates[i].Subject, subject)
+				}
+			}
+			if !reflect.DeepEqual(chain.SkippedBlocks, tt.wantSkipped) {
+				t.Errorf("SkippedBlocks = %+v, want %+v", chain.SkippedBlocks, tt.wantSkipped)
+			}
+		})
+	}
+
+	if _, err := ParsePEM([]byte("not a certificate")); err == nil || !strings.Contains(err.Error(), "no certificates found") {
+		t.Errorf("ParsePEM() on garbage: expected no certificates error, got %v", err)
+	}
+}
+
 func TestParsePEMFile(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -0,0 +1,125 @@
+package tlsquery
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"fmt"
+
+	"github.com/tlsctl/internal/pkcs12"
+)
+
+// PKCS12Info describes the contents of a PKCS#12 (.p12/.pfx) file.
+type PKCS12Info struct {
+	MAC  *PKCS12MAC  `json:"mac,omitempty"`
+	Bags []PKCS12Bag `json:"bags"`
+}
+
+// PKCS12MAC describes the integrity protection of a PKCS#12 file.
+type PKCS12MAC struct {
+	Algorithm  string `json:"algorithm"`
+	Iterations int    `json:"iterations"`
+	SaltLength int    `json:"salt_length"`
+}
+
+// PKCS12Bag describes a single bag. Certificate is set for certificate
+// bags and PrivateKey for key bags.
+type PKCS12Bag struct {
+	Type                 string         `json:"type"`
+	FriendlyName         string         `json:"friendly_name,omitempty"`
+	LocalKeyID           string         `json:"local_key_id,omitempty"`
+	Encryption           string         `json:"encryption,omitempty"`
+	EncryptionIterations int            `json:"encryption_iterations,omitempty"`
+	Certificate          *CertInfo      `json:"certificate,omitempty"`
+	PrivateKey           *PKCS12KeyInfo `json:"private_key,omitempty"`
+}
+
+// PKCS12KeyInfo describes a private key and the certificate it belongs to.
+// CertificateBag is the 1-based position of that certificate's bag, or zero
+// if no certificate in the file matches the key.
+type PKCS12KeyInfo struct {
+	Algorithm          string `json:"algorithm"`
+	CertificateBag     int    `json:"certificate_bag,omitempty"`
+	CertificateSubject string `json:"certificate_subject,omitempty"`
+}
+
+// PKCS12InfoFromStore describes a decoded PKCS#12 store. Each private key is
+// matched to the certificate holding its public key, or to the certificate
+// with the same local key ID for key types whose public key is unknown.
+func PKCS12InfoFromStore(store *pkcs12.Store) *PKCS12Info {
+	info := &PKCS12Info{Bags: make([]PKCS12Bag, 0, len(store.Bags))}
+	if store.MAC != nil {
+		info.MAC = &PKCS12MAC{
+			Algorithm:  store.MAC.Algorithm,
+			Iterations: store.MAC.Iterations,
+			SaltLength: store.MAC.SaltLength,
+		}
+	}
+
+	for _, bag := range store.Bags {
+		b := PKCS12Bag{
+			Type:         bag.Type,
+			FriendlyName: bag.FriendlyName,
+			LocalKeyID:   formatKeyID(bag.LocalKeyID),
+		}
+		if bag.Encryption != nil {
+			b.Encryption = bag.Encryption.Algorithm
+			b.EncryptionIterations = bag.Encryption.Iterations
+		}
+		if bag.Certificate != nil {
+			cert := CertInfoFromCert(bag.Certificate)
+			cert.Source = SourceFile
+			b.Certificate = &cert
+		}
+		if bag.PrivateKey != nil {
+			b.PrivateKey = &PKCS12KeyInfo{Algorithm: privateKeyAlgorithm(bag.PrivateKey)}
+			if i := matchKeyBag(store.Bags, &bag); i >= 0 {
+				b.PrivateKey.CertificateBag = i + 1
+				b.PrivateKey.CertificateSubject = store.Bags[i].Certificate.Subject.String()
+			}
+		}
+		info.Bags = append(info.Bags, b)
+	}
+
+	return info
+}
+
+// matchKeyBag returns the index of the certificate bag belonging to the
+// private key in key, or -1.
+func matchKeyBag(bags []pkcs12.Bag, key *pkcs12.Bag) int {
+	if signer, ok := key.PrivateKey.(crypto.Signer); ok {
+		if public, ok := signer.Public().(interface{ Equal(crypto.PublicKey) bool }); ok {
+			for i, bag := range bags {
+				if bag.Certificate != nil && public.Equal(bag.Certificate.PublicKey) {
+					return i
+				}
+			}
+			return -1
+		}
+	}
+	if len(key.LocalKeyID) == 0 {
+		return -1
+	}
+	for i, bag := range bags {
+		if bag.Certificate != nil && bytes.Equal(bag.LocalKeyID, key.LocalKeyID) {
+			return i
+		}
+	}
+	return -1
+}
+
+// privateKeyAlgorithm describes the type and size of a private key.
+func privateKeyAlgorithm(key crypto.PrivateKey) string {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return fmt.Sprintf("RSA %d", k.N.BitLen())
+	case *ecdsa.PrivateKey:
+		return "ECDSA " + k.Curve.Params().Name
+	case ed25519.PrivateKey:
+		return "Ed25519"
+	default:
+		return fmt.Sprintf("%T", key)
+	}
+}
@@ -0,0 +1,61 @@
+package tlsquery
+
+import (
+	"testing"
+
+	"github.com/tlsctl/internal/pkcs12"
+)
+
+func TestPKCS12InfoFromStore(t *testing.T) {
+	root := newTestCert(t, caTemplate("Test Root"), nil)
+	leaf := newTestCert(t, leafTemplate("localhost"), root)
+	other := newTestCert(t, leafTemplate("other"), root)
+
+	store := &pkcs12.Store{
+		MAC: &pkcs12.MAC{Algorithm: "SHA-256", Iterations: 2048, SaltLength: 8},
+		Bags: []pkcs12.Bag{
+			{Type: pkcs12.CertBag, Certificate: root.cert},
+			{
+				Type:         pkcs12.CertBag,
+				FriendlyName: "server",
+				LocalKeyID:   []byte{0x01},
+				Encryption:   &pkcs12.Encryption{Algorithm: "pbeWithSHAAnd40BitRC2-CBC", Iterations: 2048},
+				Certificate:  leaf.cert,
+			},
+			{Type: pkcs12.ShroudedKeyBag, LocalKeyID: []byte{0x01}, PrivateKey: leaf.key},
+			// The local key ID points at the wrong certificate; the public
+			// key decides.
+			{Type: pkcs12.KeyBag, LocalKeyID: []byte{0x01}, PrivateKey: other.key},
+		},
+	}
+
+	info := PKCS12InfoFromStore(store)
+	if info.MAC == nil || info.MAC.Algorithm != "SHA-256" || info.MAC.Iterations != 2048 {
+		t.Errorf("unexpected MAC %+v", info.MAC)
+	}
+	if len(info.Bags) != 4 {
+		t.Fatalf("expected 4 bags, got %d", len(info.Bags))
+	}
+
+	if cert := info.Bags[0].Certificate; cert == nil || cert.Type != "root" || cert.Source != SourceFile {
+		t.Errorf("unexpected root certificate %+v", cert)
+	}
+	server := info.Bags[1]
+	if server.FriendlyName != "server" || server.LocalKeyID != "01" {
+		t.Errorf("unexpected attributes %q, %q", server.FriendlyName, server.LocalKeyID)
+	}
+	if server.Encryption != "pbeWithSHAAnd40BitRC2-CBC" || server.EncryptionIterations != 2048 {
+		t.Errorf("unexpected encryption %q, %d", server.Encryption, server.EncryptionIterations)
+	}
+	if server.Certificate == nil || server.Certificate.Subject != "CN=localhost" {
+		t.Errorf("unexpected certificate %+v", server.Certificate)
+	}
+
+	key := info.Bags[2].PrivateKey
+	if key == nil || key.Algorithm != "ECDSA P-256" || key.CertificateBag != 2 || key.CertificateSubject != "CN=localhost" {
+		t.Errorf("unexpected private key %+v", key)
+	}
+	if key := info.Bags[3].PrivateKey; key == nil || key.CertificateBag != 0 {
+		t.Errorf("expected unmatched private key, got %+v", key)
+	}
+}
@@ -0,0 +1,60 @@
+package tlsquery
+
+import (
+	"encoding/asn1"
+	"testing"
+)
+
+// newPKCS7 builds a degenerate "certs-only" PKCS#7 SignedData bundle.
+func newPKCS7(t *testing.T, certs ...*testCert) []byte {
+	t.Helper()
+	var raw []byte
+	for _, c := range certs {
+		raw = append(raw, c.cert.Raw...)
+	}
+	emptySet := asn1.RawValue{Tag: asn1.TagSet, IsCompound: true}
+	signedData, err := asn1.Marshal(struct {
+		Version          int
+		DigestAlgorithms asn1.RawValue
+		ContentInfo      struct{ ContentType asn1.ObjectIdentifier }
+		Certificates     asn1.RawValue
+		SignerInfos      asn1.RawValue
+	}{
+		Version:          1,
+		DigestAlgorithms: emptySet,
+		ContentInfo:      struct{ ContentType asn1.ObjectIdentifier }{asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}},
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: raw},
+		SignerInfos:      emptySet,
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal signed data: %v", err)
+	}
+	der, err := asn1.Marshal(struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue
+	}{oidSignedData, asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: signedData}})
+	if err != nil {
+		t.Fatalf("failed to marshal content info: %v", err)
+	}
+	return der
+}
+
+func TestParsePKCS7Certificates(t *testing.T) {
+	root := newTestCert(t, caTemplate("Test Root"), nil)
+	intermediate := newTestCert(t, caTemplate("Test Intermediate"), root)
+
+	certs, err := parsePKCS7Certificates(newPKCS7(t, intermediate, root))
+	if err != nil {
+		t.Fatalf("parsePKCS7Certificates failed: %v", err)
+	}
+	if len(certs) != 2 || !certs[0].Equal(intermediate.cert) || !certs[1].Equal(root.cert) {
+		t.Errorf("unexpected certificates %v", certs)
+	}
+
+	if _, err := parsePKCS7Certificates(root.cert.Raw); err == nil {
+		t.Error("expected error for a certificate that is not PKCS#7")
+	}
+	if _, err := parsePKCS7Certificates(newPKCS7(t)); err == nil {
+		t.Error("expected error for a bundle without certificates")
+	}
+}
@@ -0,0 +1,269 @@
+package tlsquery
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// dial opens a TCP connection to endpoint, tunnelled through opts.Proxy if set.
+func dial(ctx context.Context, endpoint string, opts QueryOptions) (net.Conn, error) {
+	dialer := opts.Dialer
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
+	if opts.Proxy == nil {
+		return dialer.DialContext(ctx, "tcp", endpoint)
+	}
+
+	var tunnel func(conn net.Conn, proxy *url.URL, endpoint string) (net.Conn, error)
+	defaultPort := ""
+	switch opts.Proxy.Scheme {
+	case "http":
+		tunnel, defaultPort = tunnelHTTP, "8080"
+	case "socks5", "socks5h":
+		tunnel, defaultPort = tunnelSOCKS5, "1080"
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q (valid: http, socks5, socks5h)", opts.Proxy.Scheme)
+	}
+
+	// socks5:// resolves the target locally, socks5h:// leaves it to the proxy.
+	if opts.Proxy.Scheme == "socks5" {
+		resolved, err := resolveEndpoint(ctx, endpoint)
+		if err != nil {
+			return nil, err
+		}
+		endpoint = resolved
+	}
+
+	proxyAddr := opts.Proxy.Host
+	if opts.Proxy.Port() == "" {
+		proxyAddr = net.JoinHostPort(opts.Proxy.Hostname(), defaultPort)
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, fmt.Errorf("proxy: %w", err)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	tunnelled, err := tunnel(conn, opts.Proxy, endpoint)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("proxy: %w", err)
+	}
+	conn.SetDeadline(time.Time{})
+	return tunnelled, nil
+}
+
+// resolveEndpoint replaces the host name of endpoint with its first address.
+func resolveEndpoint(ctx context.Context, endpoint string) (string, error) {
+	host, port, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return "", err
+	}
+	if net.ParseIP(host) != nil {
+		return endpoint, nil
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return "", err
+	}
+	if len(addrs) == 0 {
+		return "", fmt.Errorf("no addresses found for %s", host)
+	}
+	return net.JoinHostPort(addrs[0].IP.String(), port), nil
+}
+
+// bufferedConn is a net.Conn whose reads are served from r first. r may hold
+// data the server sent right after the proxy's response, such as a STARTTLS
+// greeting.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+// tunnelHTTP asks an HTTP proxy to CONNECT to endpoint.
+func tunnelHTTP(conn net.Conn, proxy *url.URL, endpoint string) (net.Conn, error) {
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: endpoint},
+		Host:   endpoint,
+		Header: make(http.Header),
+	}
+	if proxy.User != nil {
+		password, _ := proxy.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(proxy.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		return nil, err
+	}
+
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("CONNECT %s: %s", endpoint, resp.Status)
+	}
+	if r.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, r: r}, nil
+	}
+	return conn, nil
+}
+
+// SOCKS5 protocol constants (RFC 1928, RFC 1929).
+const (
+	socks5Version          = 0x05
+	socks5AuthNone         = 0x00
+	socks5AuthPassword     = 0x02
+	socks5AuthNoAcceptable = 0xff
+	socks5CmdConnect       = 0x01
+	socks5AddrIPv4         = 0x01
+	socks5AddrDomain       = 0x03
+	socks5AddrIPv6         = 0x04
+)
+
+// errNotSOCKS5 is returned when the proxy does not answer with SOCKS5 replies.
+var errNotSOCKS5 = errors.New("not a SOCKS5 proxy")
+
+// tunnelSOCKS5 asks a SOCKS5 proxy to connect to endpoint.
+func tunnelSOCKS5(conn net.Conn, proxy *url.URL, endpoint string) (net.Conn, error) {
+	if err := socks5Connect(conn, proxy, endpoint); err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+func socks5Connect(conn net.Conn, proxy *url.URL, endpoint string) error {
+	host, portStr, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return fmt.Errorf("invalid port %q", portStr)
+	}
+
+	methods := []byte{socks5AuthNone}
+	if proxy.User != nil {
+		methods = append(methods, socks5AuthPassword)
+	}
+	greeting := append([]byte{socks5Version, byte(len(methods))}, methods...)
+	if _, err := conn.Write(greeting); err != nil {
+		return err
+	}
+
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[0] != socks5Version {
+		return errNotSOCKS5
+	}
+	switch reply[1] {
+	case socks5AuthNone:
+	case socks5AuthPassword:
+		if proxy.User == nil {
+			return fmt.Errorf("SOCKS5 proxy requires authentication")
+		}
+		if err := socks5Authenticate(conn, proxy.User); err != nil {
+			return err
+		}
+	case socks5AuthNoAcceptable:
+		return fmt.Errorf("SOCKS5 proxy accepted none of the offered authentication methods")
+	default:
+		return fmt.Errorf("unsupported SOCKS5 authentication method %d", reply[1])
+	}
+
+	request := []byte{socks5Version, socks5CmdConnect, 0x00}
+	if ip := net.ParseIP(host); ip == nil {
+		if len(host) > 255 {
+			return fmt.Errorf("hostname too long for SOCKS5: %q", host)
+		}
+		request = append(request, socks5AddrDomain, byte(len(host)))
+		request = append(request, host...)
+	} else if ip4 := ip.To4(); ip4 != nil {
+		request = append(request, socks5AddrIPv4)
+		request = append(request, ip4...)
+	} else {
+		request = append(request, socks5AddrIPv6)
+		request = append(request, ip.To16()...)
+	}
+	request = binary.BigEndian.AppendUint16(request, uint16(port))
+	if _, err := conn.Write(request); err != nil {
+		return err
+	}
+
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return err
+	}
+	if header[0] != socks5Version {
+		return errNotSOCKS5
+	}
+	if header[1] != 0x00 {
+		return fmt.Errorf("SOCKS5 connect to %s failed with reply code %d", endpoint, header[1])
+	}
+
+	// Skip the bound address and port.
+	var skip int
+	switch header[3] {
+	case socks5AddrIPv4:
+		skip = net.IPv4len + 2
+	case socks5AddrIPv6:
+		skip = net.IPv6len + 2
+	case socks5AddrDomain:
+		n := make([]byte, 1)
+		if _, err := io.ReadFull(conn, n); err != nil {
+			return err
+		}
+		skip = int(n[0]) + 2
+	default:
+		return fmt.Errorf("unexpected SOCKS5 address type %d", header[3])
+	}
+	_, err = io.ReadFull(conn, make([]byte, skip))
+	return err
+}
+
+func socks5Authenticate(conn net.Conn, user *url.Userinfo) error {
+	username := user.Username()
+	password, _ := user.Password()
+	if len(username) > 255 || len(password) > 255 {
+		return fmt.Errorf("SOCKS5 credentials too long")
+	}
+
+	request := []byte{0x01, byte(len(username))}
+	request = append(request, username...)
+	request = append(request, byte(len(password)))
+	request = append(request, password...)
+	if _, err := conn.Write(request); err != nil {
+		return err
+	}
+
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[1] != 0x00 {
+		return fmt.Errorf("SOCKS5 authentication failed")
+	}
+	return nil
+}
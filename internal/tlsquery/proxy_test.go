@@ -0,0 +1,289 @@
+package tlsquery
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startProxy accepts connections, runs handshake to learn the target and
+// then relays data between the client and the target.
+func startProxy(t *testing.T, handshake func(conn net.Conn, r *bufio.Reader) (string, bool)) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				target, ok := handshake(conn, r)
+				if !ok {
+					return
+				}
+				upstream, err := net.Dial("tcp", target)
+				if err != nil {
+					return
+				}
+				defer upstream.Close()
+				go io.Copy(upstream, r)
+				io.Copy(conn, upstream)
+			}()
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func httpConnectProxy(wantAuth string) func(net.Conn, *bufio.Reader) (string, bool) {
+	return func(conn net.Conn, r *bufio.Reader) (string, bool) {
+		req, err := http.ReadRequest(r)
+		if err != nil || req.Method != http.MethodConnect {
+			return "", false
+		}
+		if req.Header.Get("Proxy-Authorization") != wantAuth {
+			io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\n\r\n")
+			return "", false
+		}
+		io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+		return req.Host, true
+	}
+}
+
+// socks5Proxy is a SOCKS5 handshake. The requested hosts are sent to hosts
+// if it is not nil.
+func socks5Proxy(user, password string, hosts chan<- string) func(net.Conn, *bufio.Reader) (string, bool) {
+	return func(conn net.Conn, r *bufio.Reader) (string, bool) {
+		header := make([]byte, 2)
+		if _, err := io.ReadFull(r, header); err != nil {
+			return "", false
+		}
+		methods := make([]byte, header[1])
+		io.ReadFull(r, methods)
+
+		if user == "" {
+			conn.Write([]byte{socks5Version, socks5AuthNone})
+		} else {
+			conn.Write([]byte{socks5Version, socks5AuthPassword})
+			auth := make([]byte, 2)
+			io.ReadFull(r, auth)
+			name := make([]byte, auth[1])
+			io.ReadFull(r, name)
+			plen, _ := r.ReadByte()
+			pass := make([]byte, plen)
+			io.ReadFull(r, pass)
+			if string(name) != user || string(pass) != password {
+				conn.Write([]byte{0x01, 0x01})
+				return "", false
+			}
+			conn.Write([]byte{0x01, 0x00})
+		}
+
+		request := make([]byte, 4)
+		if _, err := io.ReadFull(r, request); err != nil {
+			return "", false
+		}
+		var host string
+		switch request[3] {
+		case socks5AddrIPv4:
+			ip := make([]byte, net.IPv4len)
+			io.ReadFull(r, ip)
+			host = net.IP(ip).String()
+		case socks5AddrIPv6:
+			ip := make([]byte, net.IPv6len)
+			io.ReadFull(r, ip)
+			host = net.IP(ip).String()
+		case socks5AddrDomain:
+			n, _ := r.ReadByte()
+			name := make([]byte, n)
+			io.ReadFull(r, name)
+			host = string(name)
+		default:
+			return "", false
+		}
+		port := make([]byte, 2)
+		io.ReadFull(r, port)
+		if hosts != nil {
+			hosts <- host
+		}
+
+		conn.Write([]byte{socks5Version, 0x00, 0x00, socks5AddrIPv4, 0, 0, 0, 0, 0, 0})
+		return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))), true
+	}
+}
+
+func TestQueryContext_Proxy(t *testing.T) {
+	server, addr := startTestTLSServer(t, false)
+	defer server.Close()
+
+	basic := "Basic " + base64.StdEncoding.EncodeToString([]byte("alice:secret"))
+
+	tests := []struct {
+		name      string
+		handshake func(net.Conn, *bufio.Reader) (string, bool)
+		proxyURL  string
+		wantError string
+	}{
+		{"http", httpConnectProxy(""), "http://%s", ""},
+		{"http with auth", httpConnectProxy(basic), "http://alice:secret@%s", ""},
+		{"http auth rejected", httpConnectProxy(basic), "http://%s", "407"},
+		{"socks5", socks5Proxy("", "", nil), "socks5://%s", ""},
+		{"socks5h", socks5Proxy("", "", nil), "socks5h://%s", ""},
+		{"socks5 with auth", socks5Proxy("alice", "secret", nil), "socks5://alice:secret@%s", ""},
+		{"socks5 auth rejected", socks5Proxy("alice", "secret", nil), "socks5://alice:wrong@%s", "authentication failed"},
+		{"unsupported scheme", httpConnectProxy(""), "ftp://%s", "unsupported proxy scheme"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxyAddr := startProxy(t, tt.handshake)
+			proxyURL, err := url.Parse(strings.Replace(tt.proxyURL, "%s", proxyAddr, 1))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			chain, err := QueryContext(context.Background(), addr, QueryOptions{Proxy: proxyURL})
+			if tt.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+					t.Errorf("expected error containing %q, got %v", tt.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("QueryContext through proxy failed: %v", err)
+			}
+			if chain.Certificates[0].CommonName != "test.example.com" {
+				t.Errorf("unexpected certificate %q", chain.Certificates[0].CommonName)
+			}
+		})
+	}
+}
+
+func TestDial_SOCKS5Resolution(t *testing.T) {
+	target, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer target.Close()
+	_, port, _ := net.SplitHostPort(target.Addr().String())
+
+	tests := []struct {
+		scheme   string
+		resolved bool
+	}{
+		{"socks5", true},
+		{"socks5h", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.scheme, func(t *testing.T) {
+			hosts := make(chan string, 1)
+			proxyURL := &url.URL{Scheme: tt.scheme, Host: startProxy(t, socks5Proxy("", "", hosts))}
+
+			conn, err := dial(context.Background(), net.JoinHostPort("localhost", port), QueryOptions{Proxy: proxyURL})
+			if err != nil {
+				t.Fatalf("dial failed: %v", err)
+			}
+			conn.Close()
+
+			host := <-hosts
+			if resolved := net.ParseIP(host) != nil; resolved != tt.resolved {
+				t.Errorf("proxy was asked for %q, want resolved=%t", host, tt.resolved)
+			}
+		})
+	}
+}
+
+// TestQueryContext_HTTPProxyGreeting sends the SMTP greeting in the same
+// write as the proxy's 200 response.
+func TestQueryContext_HTTPProxyGreeting(t *testing.T) {
+	smtpAddr := startPlaintextServer(t, fakeSMTP)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		req, err := http.ReadRequest(r)
+		if err != nil {
+			return
+		}
+		upstream, err := net.Dial("tcp", req.Host)
+		if err != nil {
+			return
+		}
+		defer upstream.Close()
+		upstreamReader := bufio.NewReader(upstream)
+		greeting := readLine(upstreamReader) + "\r\n" + readLine(upstreamReader) + "\r\n"
+		io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n"+greeting)
+		go io.Copy(upstream, r)
+		io.Copy(conn, upstreamReader)
+	}()
+
+	opts := QueryOptions{
+		StartTLS: "smtp",
+		Proxy:    &url.URL{Scheme: "http", Host: listener.Addr().String()},
+		Timeout:  5 * time.Second,
+	}
+	chain, err := QueryContext(context.Background(), smtpAddr, opts)
+	if err != nil {
+		t.Fatalf("QueryContext through proxy failed: %v", err)
+	}
+	if chain.Certificates[0].CommonName != "mail.example.com" {
+		t.Errorf("unexpected certificate %q", chain.Certificates[0].CommonName)
+	}
+}
+
+func TestQueryContext_NotSOCKS5Proxy(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply []byte
+	}{
+		{"HTTP proxy", []byte("HTTP/1.1 400 Bad Request\r\n\r\n")},
+		{"bad CONNECT reply", []byte{socks5Version, socks5AuthNone, 0x00, 0x5a, 0x00, 0x00, 0, 0, 0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxyAddr := startProxy(t, func(conn net.Conn, r *bufio.Reader) (string, bool) {
+				r.Read(make([]byte, 16))
+				conn.Write(tt.reply)
+				io.Copy(io.Discard, r)
+				return "", false
+			})
+
+			opts := QueryOptions{
+				Proxy:   &url.URL{Scheme: "socks5h", Host: proxyAddr},
+				Timeout: 5 * time.Second,
+			}
+			_, err := QueryContext(context.Background(), "example.com:443", opts)
+			if err == nil || !strings.Contains(err.Error(), "not a SOCKS5 proxy") {
+				t.Errorf("expected not a SOCKS5 proxy error, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package tlsquery
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+)
+
+// PublicKeyInfo describes the public key of a certificate, CSR or private
+// key. The SPKI fingerprint is the SHA-256 hash of the DER
+// SubjectPublicKeyInfo, in hex and in base64 as used by HPKP-style pins.
+type PublicKeyInfo struct {
+	Algorithm        string `json:"algorithm"`
+	Bits             int    `json:"bits,omitempty"`
+	Curve            string `json:"curve,omitempty"`
+	Exponent         int    `json:"exponent,omitempty"`
+	SPKISHA256       string `json:"spki_sha256"`
+	SPKISHA256Base64 string `json:"spki_sha256_base64"`
+}
+
+// PublicKeyInfoFromCert describes the public key of cert. Keys of
+// algorithms unknown to crypto/x509 are named by their algorithm OID.
+func PublicKeyInfoFromCert(cert *x509.Certificate) *PublicKeyInfo {
+	return newPublicKeyInfo(cert.PublicKey, cert.PublicKeyAlgorithm, cert.RawSubjectPublicKeyInfo)
+}
+
+// newPublicKeyInfo describes pub of the given algorithm, whose DER
+// SubjectPublicKeyInfo is rawSPKI.
+func newPublicKeyInfo(pub crypto.PublicKey, algorithm x509.PublicKeyAlgorithm, rawSPKI []byte) *PublicKeyInfo {
+	sum := sha256.Sum256(rawSPKI)
+	info := &PublicKeyInfo{
+		Algorithm:        algorithm.String(),
+		SPKISHA256:       formatFingerprint(sum[:]),
+		SPKISHA256Base64: base64.StdEncoding.EncodeToString(sum[:]),
+	}
+	info.Bits, info.Curve = publicKeySize(pub)
+	if key, ok := pub.(*rsa.PublicKey); ok {
+		info.Exponent = key.E
+	}
+
+	if algorithm == x509.UnknownPublicKeyAlgorithm {
+		var spki struct {
+			Algorithm pkix.AlgorithmIdentifier
+			PublicKey asn1.BitString
+		}
+		if _, err := asn1.Unmarshal(rawSPKI, &spki); err == nil {
+			info.Algorithm = spki.Algorithm.Algorithm.String()
+		}
+	}
+	return info
+}
+
+// publicKeySize returns the size in bits of a public key and, for elliptic
+// curve keys, the curve name.
+func publicKeySize(pub crypto.PublicKey) (int, string) {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return k.N.BitLen(), ""
+	case *ecdsa.PublicKey:
+		return k.Curve.Params().BitSize, k.Curve.Params().Name
+	case ed25519.PublicKey:
+		return 256, "Ed25519"
+	default:
+		return 0, ""
+	}
+}
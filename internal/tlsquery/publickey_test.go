@@ -0,0 +1,85 @@
+package tlsquery
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"testing"
+)
+
+func TestPublicKeyInfoFromCert(t *testing.T) {
+	ecCert := newTestCert(t, leafTemplate("ecdsa"), nil).cert
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	selfSigned := func(key any, pub any) *x509.Certificate {
+		template := leafTemplate("key")
+		der, err := x509.CreateCertificate(rand.Reader, template, template, pub, key)
+		if err != nil {
+			t.Fatalf("failed to create certificate: %v", err)
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cert
+	}
+	rsaCert := selfSigned(rsaKey, &rsaKey.PublicKey)
+	edCert := selfSigned(edKey, edKey.Public())
+
+	// crypto/x509 leaves the key of unknown algorithms unparsed.
+	unknownSPKI, err := asn1.Marshal(struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}{
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{1, 2, 3, 4}},
+		PublicKey: asn1.BitString{Bytes: []byte{1, 2, 3}, BitLength: 24},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknownCert := &x509.Certificate{RawSubjectPublicKeyInfo: unknownSPKI}
+
+	tests := []struct {
+		name          string
+		cert          *x509.Certificate
+		wantAlgorithm string
+		wantBits      int
+		wantCurve     string
+		wantExponent  int
+	}{
+		{"RSA", rsaCert, "RSA", 2048, "", 65537},
+		{"ECDSA", ecCert, "ECDSA", 256, "P-256", 0},
+		{"Ed25519", edCert, "Ed25519", 256, "Ed25519", 0},
+		{"unknown", unknownCert, "1.2.3.4", 0, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := PublicKeyInfoFromCert(tt.cert)
+			if info.Algorithm != tt.wantAlgorithm || info.Bits != tt.wantBits || info.Curve != tt.wantCurve || info.Exponent != tt.wantExponent {
+				t.Errorf("got %s %d %q e=%d, want %s %d %q e=%d", info.Algorithm, info.Bits, info.Curve, info.Exponent,
+					tt.wantAlgorithm, tt.wantBits, tt.wantCurve, tt.wantExponent)
+			}
+
+			sum := sha256.Sum256(tt.cert.RawSubjectPublicKeyInfo)
+			if info.SPKISHA256 != formatFingerprint(sum[:]) {
+				t.Errorf("SPKISHA256 = %s, want %s", info.SPKISHA256, formatFingerprint(sum[:]))
+			}
+			if want := base64.StdEncoding.EncodeToString(sum[:]); info.SPKISHA256Base64 != want {
+				t.Errorf("SPKISHA256Base64 = %s, want %s", info.SPKISHA256Base64, want)
+			}
+		})
+	}
+}
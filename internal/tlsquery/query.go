@@ -1,6 +1,7 @@
 package tlsquery
 
 import (
+	"context"
 	"crypto/sha1"
 	"crypto/sha256"
 	"crypto/tls"
@@ -8,6 +9,7 @@ import (
 	"encoding/pem"
 	"fmt"
 	"net"
+	"net/http"
 	"strings"
 	"time"
 )
@@ -15,6 +17,7 @@ import (
 // CertInfo holds the extracted certificate metadata.
 type CertInfo struct {
 	Type               string            `json:"type"`
+	Source             string            `json:"source,omitempty"`
 	Version            int               `json:"version"`
 	SerialNumber       string            `json:"serial_number"`
 	SignatureAlgorithm string            `json:"signature_algorithm"`
@@ -24,6 +27,7 @@ type CertInfo struct {
 	NotBefore          string            `json:"not_before"`
 	NotAfter           string            `json:"not_after"`
 	PublicKeyAlgorithm string            `json:"public_key_algorithm"`
+	PublicKey          *PublicKeyInfo    `json:"public_key"`
 	KeyUsage           []string          `json:"key_usage,omitempty"`
 	ExtKeyUsage        []string          `json:"extended_key_usage,omitempty"`
 	BasicConstraints   *BasicConstraints `json:"basic_constraints,omitempty"`
@@ -35,7 +39,11 @@ type CertInfo struct {
 	OCSPServers        []string          `json:"ocsp_servers,omitempty"`
 	IssuingCertURL     []string          `json:"issuing_cert_url,omitempty"`
 	CRLDistPoints      []string          `json:"crl_distribution_points,omitempty"`
+	SCTs               []SCT             `json:"scts,omitempty"`
 	Fingerprint        Fingerprint       `json:"fingerprint"`
+	AIAError           string            `json:"aia_error,omitempty"`
+	OCSP               *OCSPResult       `json:"ocsp,omitempty"`
+	CRL                *CRLResult        `json:"crl,omitempty"`
 	PEM                string            `json:"pem,omitempty"`
 }
 
@@ -53,40 +61,97 @@ type BasicConstraints struct {
 
 // ChainInfo holds the full certificate chain.
 type ChainInfo struct {
-	Certificates []CertInfo `json:"certificates"`
-}
+	Certificates      []CertInfo         `json:"certificates"`
+	Connection        *ConnectionInfo    `json:"connection,omitempty"`
+	StapledOCSP       *StapledOCSP       `json:"stapled_ocsp,omitempty"`
+	ClientCertRequest *ClientCertRequest `json:"client_certificate_request,omitempty"`
+	Analysis          *ChainAnalysis     `json:"chain_analysis,omitempty"`
+	SkippedBlocks     []SkippedBlock     `json:"skipped_blocks,omitempty"`
+	Verification      *Verification      `json:"verification,omitempty"`
+	// CompletedVerification validates the chain with the issuers fetched
+	// from AIA URLs. It is only set when issuers were fetched; Verification
+	// always covers the chain as served.
+	CompletedVerification *Verification `json:"completed_verification,omitempty"`
 
-// TLSConfig allows customizing the TLS configuration for testing.
-var TLSConfig *tls.Config
+	// certs are the parsed certificates, in the same order as Certificates.
+	certs []*x509.Certificate
+}
 
 // Query connects to the given endpoint and retrieves certificate chain information.
 func Query(endpoint string) (*ChainInfo, error) {
-	config := TLSConfig
-	if config == nil {
-		config = &tls.Config{}
+	return QueryContext(context.Background(), endpoint, QueryOptions{})
+}
+
+// QueryContext connects to the given endpoint and retrieves certificate
+// chain information. The handshake does not enforce verification, so chains
+// that fail validation are still returned along with the failure reason.
+// If the server requests a client certificate, the request is reported in
+// ChainInfo, or in a *ClientAuthError when the handshake fails.
+func QueryContext(ctx context.Context, endpoint string, opts QueryOptions) (*ChainInfo, error) {
+	if opts.StartTLS != "" {
+		if _, err := lookupStartTLS(opts.StartTLS); err != nil {
+			return nil, err
+		}
+	}
+
+	config := clientConfig(endpoint, opts)
+	if opts.CheckResumption {
+		config.ClientSessionCache = tls.NewLRUClientSessionCache(1)
 	}
-	conn, err := tls.Dial("tcp", endpoint, config)
+	var certRequest *ClientCertRequest
+	recordClientCertRequest(config, opts.Certificates, &certRequest)
+
+	conn, err := handshake(ctx, endpoint, config, opts)
 	if err != nil {
-		return nil, fmt.Errorf("TLS handshake failed: %w", err)
+		if certRequest != nil {
+			return nil, &ClientAuthError{Request: certRequest, Err: err}
+		}
+		return nil, err
 	}
 	defer conn.Close()
 
-	certs := conn.ConnectionState().PeerCertificates
+	state := conn.ConnectionState()
+	certs := state.PeerCertificates
 	if len(certs) == 0 {
 		return nil, fmt.Errorf("no certificate returned by server")
 	}
 
 	chain := &ChainInfo{
-		Certificates: make([]CertInfo, 0, len(certs)),
+		Certificates:      make([]CertInfo, 0, len(certs)),
+		Connection:        ConnectionInfoFromState(state),
+		ClientCertRequest: certRequest,
+		certs:             certs,
+	}
+
+	if opts.CheckResumption {
+		resumed := checkResumption(ctx, conn, endpoint, config, opts)
+		chain.Connection.ResumptionSupported = &resumed
 	}
 
 	for i, cert := range certs {
 		chain.Certificates = append(chain.Certificates, CertInfoFromCert(cert))
+		chain.Certificates[i].Source = SourceServed
 		if i == 0 {
 			chain.Certificates[i].Type = "leaf"
 		}
 	}
 
+	chain.Analysis = AnalyzeChain(certs)
+	chain.Verification = VerifyChain(certs, config.ServerName, opts.RootCAs)
+	if opts.FetchIssuers {
+		CompleteChain(ctx, chain, &http.Client{Timeout: opts.Timeout}, opts.RootCAs)
+		if len(chain.certs) > len(certs) {
+			chain.Verification.IncompleteChain = true
+			chain.CompletedVerification = VerifyChain(chain.certs, config.ServerName, opts.RootCAs)
+		}
+	}
+
+	leaf := &chain.Certificates[0]
+	leaf.SCTs = append(leaf.SCTs, sctsFromTLS(state.SignedCertificateTimestamps)...)
+	leaf.SCTs = append(leaf.SCTs, sctsFromOCSP(state.OCSPResponse, certs[0])...)
+
+	chain.StapledOCSP = StapledOCSPFromResponse(state.OCSPResponse, certs[0], findIssuer(certs[0], chain.certs), time.Now())
+
 	return chain, nil
 }
 
@@ -124,6 +189,7 @@ func CertInfoFromCert(cert *x509.Certificate) CertInfo {
 		NotBefore:          cert.NotBefore.UTC().Format(time.RFC3339),
 		NotAfter:           cert.NotAfter.UTC().Format(time.RFC3339),
 		PublicKeyAlgorithm: cert.PublicKeyAlgorithm.String(),
+		PublicKey:          PublicKeyInfoFromCert(cert),
 		KeyUsage:           formatKeyUsage(cert.KeyUsage),
 		ExtKeyUsage:        formatExtKeyUsage(cert.ExtKeyUsage),
 		SubjectKeyID:       formatKeyID(cert.SubjectKeyId),
@@ -134,6 +200,7 @@ func CertInfoFromCert(cert *x509.Certificate) CertInfo {
 		OCSPServers:        cert.OCSPServer,
 		IssuingCertURL:     cert.IssuingCertificateURL,
 		CRLDistPoints:      cert.CRLDistributionPoints,
+		SCTs:               sctsFromCertificate(cert),
 		Fingerprint:        computeFingerprint(cert.Raw),
 		PEM:                encodePEM(cert.Raw),
 	}
@@ -1,6 +1,7 @@
 package tlsquery
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
@@ -17,10 +18,6 @@ func TestQuery_ValidEndpoint(t *testing.T) {
 	server, addr := startTestTLSServer(t, false)
 	defer server.Close()
 
-	oldConfig := TLSConfig
-	TLSConfig = &tls.Config{InsecureSkipVerify: true}
-	defer func() { TLSConfig = oldConfig }()
-
 	chain, err := Query(addr)
 	if err != nil {
 		t.Fatalf("Query failed: %v", err)
@@ -40,6 +37,48 @@ func TestQuery_ValidEndpoint(t *testing.T) {
 	if len(leaf.SubjectAltNames) != 2 {
 		t.Errorf("expected 2 SANs, got %d", len(leaf.SubjectAltNames))
 	}
+
+	if chain.Verification == nil {
+		t.Fatal("expected verification result")
+	}
+	if chain.Verification.Verified {
+		t.Error("expected self-signed certificate to fail verification")
+	}
+	if chain.Verification.Reason != ReasonUnknownAuthority {
+		t.Errorf("expected reason %q, got %q", ReasonUnknownAuthority, chain.Verification.Reason)
+	}
+}
+
+func TestQueryContext_RootCAs(t *testing.T) {
+	root := newTestCert(t, caTemplate("Test Root"), nil)
+	leaf := newTestCert(t, leafTemplate("localhost"), root)
+
+	server, addr := startTLSServerWithConfig(t, &tls.Config{
+		Certificates: []tls.Certificate{{
+			Certificate: [][]byte{leaf.cert.Raw},
+			PrivateKey:  leaf.key,
+		}},
+	})
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root.cert)
+
+	chain, err := QueryContext(context.Background(), addr, QueryOptions{RootCAs: roots})
+	if err != nil {
+		t.Fatalf("QueryContext failed: %v", err)
+	}
+
+	v := chain.Verification
+	if !v.Verified {
+		t.Errorf("expected chain to verify, got reason %q: %s", v.Reason, v.Error)
+	}
+	if v.Hostname != "127.0.0.1" {
+		t.Errorf("expected hostname '127.0.0.1', got %q", v.Hostname)
+	}
+	if len(v.Chains) != 1 || len(v.Chains[0]) != 2 {
+		t.Errorf("expected one verified chain of length 2, got %v", v.Chains)
+	}
 }
 
 func TestQuery_InvalidEndpoint(t *testing.T) {
@@ -56,6 +95,34 @@ func TestQuery_ConnectionRefused(t *testing.T) {
 	}
 }
 
+func TestQueryContext_Timeout(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	// Accept connections but never answer the handshake.
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	start := time.Now()
+	_, err = QueryContext(context.Background(), listener.Addr().String(), QueryOptions{Timeout: 200 * time.Millisecond})
+	if err == nil {
+		t.Fatal("expected error for stalled handshake")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("QueryContext took %v, expected the timeout to apply", elapsed)
+	}
+}
+
 func TestCertType(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -130,6 +197,12 @@ func startTestTLSServer(t *testing.T, clientAuth bool) (net.Listener, string) {
 		Certificates: []tls.Certificate{cert},
 	}
 
+	return startTLSServerWithConfig(t, tlsConfig)
+}
+
+func startTLSServerWithConfig(t *testing.T, tlsConfig *tls.Config) (net.Listener, string) {
+	t.Helper()
+
 	listener, err := tls.Listen("tcp", "127.0.0.1:0", tlsConfig)
 	if err != nil {
 		t.Fatalf("failed to start TLS listener: %v", err)
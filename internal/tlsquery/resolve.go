@@ -0,0 +1,67 @@
+package tlsquery
+
+import (
+	"context"
+	"fmt"
+	"net"
+)
+
+// ResolveResult holds the certificates served by every address of a hostname.
+type ResolveResult struct {
+	Hostname   string          `json:"hostname"`
+	Port       string          `json:"port"`
+	Consistent bool            `json:"consistent"`
+	Addresses  []AddressResult `json:"addresses"`
+}
+
+// AddressResult holds the query result for a single resolved address.
+type AddressResult struct {
+	Address     string     `json:"address"`
+	Fingerprint string     `json:"leaf_sha256,omitempty"`
+	Chain       *ChainInfo `json:"chain,omitempty"`
+	Error       string     `json:"error,omitempty"`
+}
+
+// lookupIPAddr is replaced in tests.
+var lookupIPAddr = net.DefaultResolver.LookupIPAddr
+
+// QueryAllAddresses resolves the host of endpoint to all of its A and AAAA
+// records and queries each address, using the hostname as server name.
+// Consistent reports whether every address served the same leaf certificate.
+func QueryAllAddresses(ctx context.Context, endpoint string, opts QueryOptions) (*ResolveResult, error) {
+	host, port, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs, err := lookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", host, err)
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no addresses found for %s", host)
+	}
+
+	if opts.ServerName == "" {
+		opts.ServerName = host
+	}
+
+	result := &ResolveResult{Hostname: host, Port: port, Consistent: true}
+	for _, addr := range addrs {
+		ar := AddressResult{Address: addr.String()}
+		chain, err := QueryContext(ctx, net.JoinHostPort(addr.String(), port), opts)
+		if err != nil {
+			ar.Error = err.Error()
+			result.Consistent = false
+		} else {
+			ar.Chain = chain
+			ar.Fingerprint = chain.Certificates[0].Fingerprint.SHA256
+			if len(result.Addresses) > 0 && result.Addresses[0].Fingerprint != ar.Fingerprint {
+				result.Consistent = false
+			}
+		}
+		result.Addresses = append(result.Addresses, ar)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,95 @@
+package tlsquery
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"testing"
+)
+
+func fakeLookup(t *testing.T, ips ...string) {
+	t.Helper()
+	old := lookupIPAddr
+	lookupIPAddr = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		addrs := make([]net.IPAddr, len(ips))
+		for i, ip := range ips {
+			addrs[i] = net.IPAddr{IP: net.ParseIP(ip)}
+		}
+		return addrs, nil
+	}
+	t.Cleanup(func() { lookupIPAddr = old })
+}
+
+func TestQueryAllAddresses(t *testing.T) {
+	leafA := newTestCert(t, leafTemplate("test.example.com"), nil)
+	leafB := newTestCert(t, leafTemplate("test.example.com"), nil)
+
+	serverA, addr := startTLSServerWithConfig(t, &tls.Config{
+		Certificates: []tls.Certificate{serverCertificate(leafA)},
+	})
+	defer serverA.Close()
+	_, port, _ := net.SplitHostPort(addr)
+
+	t.Run("same certificate everywhere", func(t *testing.T) {
+		fakeLookup(t, "127.0.0.1", "127.0.0.1")
+
+		result, err := QueryAllAddresses(context.Background(), "test.example.com:"+port, QueryOptions{})
+		if err != nil {
+			t.Fatalf("QueryAllAddresses failed: %v", err)
+		}
+		if !result.Consistent {
+			t.Errorf("expected consistent result, got %+v", result.Addresses)
+		}
+		if len(result.Addresses) != 2 {
+			t.Errorf("expected 2 addresses, got %d", len(result.Addresses))
+		}
+		if sni := result.Addresses[0].Chain.Connection.ServerName; sni != "test.example.com" {
+			t.Errorf("expected hostname to be sent as SNI, got %q", sni)
+		}
+	})
+
+	t.Run("different certificates", func(t *testing.T) {
+		listener, err := tls.Listen("tcp", "127.0.0.2:"+port, &tls.Config{
+			Certificates: []tls.Certificate{serverCertificate(leafB)},
+		})
+		if err != nil {
+			t.Skipf("cannot listen on 127.0.0.2: %v", err)
+		}
+		defer listener.Close()
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				_ = conn.(*tls.Conn).Handshake()
+				conn.Close()
+			}
+		}()
+
+		fakeLookup(t, "127.0.0.1", "127.0.0.2")
+
+		result, err := QueryAllAddresses(context.Background(), "test.example.com:"+port, QueryOptions{})
+		if err != nil {
+			t.Fatalf("QueryAllAddresses failed: %v", err)
+		}
+		if result.Consistent {
+			t.Error("expected inconsistent result for different certificates")
+		}
+	})
+
+	t.Run("unreachable address", func(t *testing.T) {
+		fakeLookup(t, "127.0.0.1", "127.0.0.3")
+
+		result, err := QueryAllAddresses(context.Background(), "test.example.com:"+port, QueryOptions{})
+		if err != nil {
+			t.Fatalf("QueryAllAddresses failed: %v", err)
+		}
+		if result.Consistent {
+			t.Error("expected inconsistent result when an address fails")
+		}
+		if result.Addresses[1].Error == "" {
+			t.Error("expected error for unreachable address")
+		}
+	})
+}
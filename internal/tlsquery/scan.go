@@ -0,0 +1,288 @@
+package tlsquery
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// ScanResult holds the protocol versions and cipher suites accepted by an endpoint.
+type ScanResult struct {
+	Endpoint string          `json:"endpoint"`
+	Versions []VersionResult `json:"versions"`
+}
+
+// VersionResult holds the scan result for a single TLS version. CipherSuites
+// are listed in the order the server selected them, which is the server's
+// preference order only if ServerPreference is true.
+type VersionResult struct {
+	Version      string            `json:"version"`
+	Supported    bool              `json:"supported"`
+	CipherSuites []CipherSuiteInfo `json:"cipher_suites,omitempty"`
+	// ServerPreference reports whether the server picks suites by its own
+	// order, tested by offering the accepted suites in reverse. It is nil
+	// when fewer than two suites were accepted or the test failed.
+	ServerPreference *bool `json:"server_preference,omitempty"`
+	// Partial is set when CipherSuites may be incomplete; Note says why.
+	Partial bool   `json:"partial,omitempty"`
+	Note    string `json:"note,omitempty"`
+}
+
+// CipherSuiteInfo describes an accepted cipher suite.
+type CipherSuiteInfo struct {
+	Name     string `json:"name"`
+	ID       string `json:"id"`
+	Insecure bool   `json:"insecure"`
+}
+
+// scanVersions are the protocol versions probed by Scan, oldest first.
+var scanVersions = []uint16{tls.VersionTLS10, tls.VersionTLS11, tls.VersionTLS12, tls.VersionTLS13}
+
+// Scan connects repeatedly to endpoint with constrained configurations and
+// reports every TLS version and cipher suite the server accepts.
+//
+// For TLS 1.0 to 1.2 the suites are enumerated by offering all remaining
+// suites and removing the one the server picks. Only suites implemented by
+// crypto/tls can be offered. TLS 1.3 suites are not configurable in
+// crypto/tls, so only the negotiated suite is reported.
+func Scan(ctx context.Context, endpoint string, opts QueryOptions) (*ScanResult, error) {
+	if opts.StartTLS != "" {
+		if _, err := lookupStartTLS(opts.StartTLS); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := checkReachable(ctx, endpoint, opts); err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+
+	result := &ScanResult{Endpoint: endpoint}
+	for _, version := range scanVersions {
+		result.Versions = append(result.Versions, scanVersion(ctx, endpoint, opts, version))
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// checkReachable makes sure endpoint accepts connections, so that failed
+// probes can be attributed to the TLS configuration.
+func checkReachable(ctx context.Context, endpoint string, opts QueryOptions) error {
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	conn, err := dial(ctx, endpoint, opts)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func scanVersion(ctx context.Context, endpoint string, opts QueryOptions, version uint16) VersionResult {
+	result := VersionResult{Version: tls.VersionName(version)}
+
+	if version == tls.VersionTLS13 {
+		id, ok := probe(ctx, endpoint, opts, version, nil)
+		if ok {
+			result.Supported = true
+			result.CipherSuites = append(result.CipherSuites, cipherSuiteInfo(id))
+			result.Partial = true
+			result.Note = "TLS 1.3 suites cannot be restricted by crypto/tls, only the negotiated suite is listed"
+		}
+		return result
+	}
+
+	var accepted []uint16
+	remaining := cipherSuitesFor(version)
+	for len(remaining) > 0 {
+		id, ok := probe(ctx, endpoint, opts, version, remaining)
+		if !ok {
+			break
+		}
+		accepted = append(accepted, id)
+		remaining = removeSuite(remaining, id)
+	}
+	if len(accepted) == 0 {
+		return result
+	}
+
+	result.Supported = true
+	for _, id := range accepted {
+		result.CipherSuites = append(result.CipherSuites, cipherSuiteInfo(id))
+	}
+	result.Partial = true
+	result.Note = "only suites implemented by crypto/tls were offered, DHE, CCM, ARIA and Camellia suites are not detected"
+
+	if len(accepted) > 1 {
+		if id, ok := probeReversed(ctx, endpoint, opts, version, accepted); ok {
+			preference := id == accepted[0]
+			result.ServerPreference = &preference
+		}
+	}
+	return result
+}
+
+// probe performs a handshake restricted to version and suites and returns
+// the cipher suite selected by the server.
+func probe(ctx context.Context, endpoint string, opts QueryOptions, version uint16, suites []uint16) (uint16, bool) {
+	config := clientConfig(endpoint, opts)
+	config.MinVersion = version
+	config.MaxVersion = version
+	config.CipherSuites = suites
+
+	conn, err := handshake(ctx, endpoint, config, opts)
+	if err != nil {
+		return 0, false
+	}
+	defer conn.Close()
+
+	state := conn.ConnectionState()
+	if state.Version != version {
+		return 0, false
+	}
+	return state.CipherSuite, true
+}
+
+// errSuiteSelected aborts a probeReversed handshake once the server has
+// selected a cipher suite.
+var errSuiteSelected = errors.New("cipher suite selected")
+
+// probeReversed offers suites in the reverse of the order crypto/tls sends
+// them and returns the cipher suite selected by the server. crypto/tls
+// ignores the order of Config.CipherSuites, so the ClientHello is rewritten
+// on the wire. That breaks the handshake transcript, so the handshake is
+// aborted as soon as the server's choice is known.
+func probeReversed(ctx context.Context, endpoint string, opts QueryOptions, version uint16, suites []uint16) (uint16, bool) {
+	var selected uint16
+	config := clientConfig(endpoint, opts)
+	config.MinVersion = version
+	config.MaxVersion = version
+	config.CipherSuites = suites
+	config.VerifyConnection = func(state tls.ConnectionState) error {
+		selected = state.CipherSuite
+		return errSuiteSelected
+	}
+
+	var rc *reverseSuitesConn
+	wrap := func(conn net.Conn) net.Conn {
+		rc = &reverseSuitesConn{Conn: conn}
+		return rc
+	}
+	conn, err := handshakeWith(ctx, endpoint, config, opts, wrap)
+	if err == nil {
+		conn.Close()
+		return 0, false
+	}
+	if !errors.Is(err, errSuiteSelected) || rc == nil || !rc.reversed {
+		return 0, false
+	}
+	return selected, true
+}
+
+// reverseSuitesConn reverses the cipher suite list of the ClientHello, which
+// crypto/tls writes first.
+type reverseSuitesConn struct {
+	net.Conn
+	written  bool
+	reversed bool
+}
+
+func (c *reverseSuitesConn) Write(b []byte) (int, error) {
+	if c.written {
+		return c.Conn.Write(b)
+	}
+	c.written = true
+
+	hello, ok := reverseCipherSuites(b)
+	if !ok {
+		return c.Conn.Write(b)
+	}
+	if _, err := c.Conn.Write(hello); err != nil {
+		return 0, err
+	}
+	c.reversed = true
+	return len(b), nil
+}
+
+// reverseCipherSuites returns a copy of a ClientHello record with the order
+// of its cipher suite list reversed.
+func reverseCipherSuites(record []byte) ([]byte, bool) {
+	const (
+		recordHeaderLen    = 5
+		handshakeHeaderLen = 4
+	)
+	if len(record) < recordHeaderLen+handshakeHeaderLen ||
+		record[0] != 0x16 || // handshake record
+		record[recordHeaderLen] != 0x01 || // client_hello
+		len(record) != recordHeaderLen+int(binary.BigEndian.Uint16(record[3:])) {
+		return nil, false
+	}
+
+	// client_version and random precede the session ID.
+	off := recordHeaderLen + handshakeHeaderLen + 2 + 32
+	if len(record) < off+1 {
+		return nil, false
+	}
+	off += 1 + int(record[off])
+	if len(record) < off+2 {
+		return nil, false
+	}
+	n := int(binary.BigEndian.Uint16(record[off:]))
+	off += 2
+	if n%2 != 0 || len(record) < off+n {
+		return nil, false
+	}
+
+	hello := append([]byte(nil), record...)
+	suites := hello[off : off+n]
+	for i, j := 0, n-2; i < j; i, j = i+2, j-2 {
+		suites[i], suites[i+1], suites[j], suites[j+1] = suites[j], suites[j+1], suites[i], suites[i+1]
+	}
+	return hello, true
+}
+
+// cipherSuitesFor returns all suites implemented by crypto/tls that can be
+// negotiated at version.
+func cipherSuitesFor(version uint16) []uint16 {
+	var ids []uint16
+	for _, suites := range [][]*tls.CipherSuite{tls.CipherSuites(), tls.InsecureCipherSuites()} {
+		for _, suite := range suites {
+			for _, v := range suite.SupportedVersions {
+				if v == version {
+					ids = append(ids, suite.ID)
+					break
+				}
+			}
+		}
+	}
+	return ids
+}
+
+func cipherSuiteInfo(id uint16) CipherSuiteInfo {
+	info := CipherSuiteInfo{
+		Name: tls.CipherSuiteName(id),
+		ID:   fmt.Sprintf("0x%04X", id),
+	}
+	for _, suite := range tls.InsecureCipherSuites() {
+		if suite.ID == id {
+			info.Insecure = true
+		}
+	}
+	return info
+}
+
+func removeSuite(suites []uint16, id uint16) []uint16 {
+	result := make([]uint16, 0, len(suites))
+	for _, s := range suites {
+		if s != id {
+			result = append(result, s)
+		}
+	}
+	return result
+}
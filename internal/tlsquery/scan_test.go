@@ -0,0 +1,157 @@
+package tlsquery
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"testing"
+)
+
+func TestScan(t *testing.T) {
+	leaf := newTestCert(t, leafTemplate("localhost"), nil)
+	server, addr := startTLSServerWithConfig(t, &tls.Config{
+		Certificates: []tls.Certificate{{
+			Certificate: [][]byte{leaf.cert.Raw},
+			PrivateKey:  leaf.key,
+		}},
+		MinVersion: tls.VersionTLS12,
+		MaxVersion: tls.VersionTLS12,
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+		},
+	})
+	defer server.Close()
+
+	result, err := Scan(context.Background(), addr, QueryOptions{})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	if len(result.Versions) != 4 {
+		t.Fatalf("expected 4 versions, got %d", len(result.Versions))
+	}
+
+	for _, v := range result.Versions {
+		if v.Version != "TLS 1.2" {
+			if v.Supported {
+				t.Errorf("%s: expected unsupported", v.Version)
+			}
+			continue
+		}
+		if !v.Supported {
+			t.Fatalf("TLS 1.2: expected supported")
+		}
+		got := map[string]bool{}
+		for _, cs := range v.CipherSuites {
+			got[cs.Name] = true
+		}
+		if len(got) != 2 ||
+			!got["TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"] ||
+			!got["TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA"] {
+			t.Errorf("TLS 1.2: unexpected cipher suites %+v", v.CipherSuites)
+		}
+		if v.ServerPreference == nil || !*v.ServerPreference {
+			t.Errorf("TLS 1.2: expected server preference, got %v", v.ServerPreference)
+		}
+		if !v.Partial || v.Note == "" {
+			t.Errorf("TLS 1.2: expected a partial result note, got %+v", v)
+		}
+	}
+}
+
+func TestScan_ClientPreference(t *testing.T) {
+	leaf := newTestCert(t, leafTemplate("localhost"), nil)
+	supported := map[uint16]bool{
+		tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256: true,
+		tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA:    true,
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{{
+			Certificate: [][]byte{leaf.cert.Raw},
+			PrivateKey:  leaf.key,
+		}},
+		MinVersion: tls.VersionTLS12,
+		MaxVersion: tls.VersionTLS12,
+	}
+	// Select the first supported suite in the client's order.
+	config.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		c := config.Clone()
+		c.GetConfigForClient = nil
+		c.CipherSuites = []uint16{}
+		for _, id := range hello.CipherSuites {
+			if supported[id] {
+				c.CipherSuites = []uint16{id}
+				break
+			}
+		}
+		return c, nil
+	}
+	server, addr := startTLSServerWithConfig(t, config)
+	defer server.Close()
+
+	result, err := Scan(context.Background(), addr, QueryOptions{})
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	for _, v := range result.Versions {
+		if v.Version != "TLS 1.2" {
+			continue
+		}
+		if len(v.CipherSuites) != 2 {
+			t.Fatalf("TLS 1.2: expected 2 cipher suites, got %+v", v.CipherSuites)
+		}
+		if v.ServerPreference == nil || *v.ServerPreference {
+			t.Errorf("TLS 1.2: expected client preference, got %v", v.ServerPreference)
+		}
+	}
+}
+
+func TestScan_ConnectionRefused(t *testing.T) {
+	if _, err := Scan(context.Background(), "127.0.0.1:1", QueryOptions{}); err == nil {
+		t.Error("expected error for connection refused")
+	}
+}
+
+func TestCipherSuitesFor(t *testing.T) {
+	for _, id := range cipherSuitesFor(tls.VersionTLS10) {
+		if id == tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256 {
+			t.Error("GCM suites must not be offered for TLS 1.0")
+		}
+	}
+	if len(cipherSuitesFor(tls.VersionTLS12)) == 0 {
+		t.Error("expected TLS 1.2 cipher suites")
+	}
+}
+
+func TestReverseCipherSuites(t *testing.T) {
+	if _, ok := reverseCipherSuites([]byte("EHLO localhost\r\n")); ok {
+		t.Error("expected non-TLS data to be left alone")
+	}
+
+	// Handshake record with a client_hello: version, random, empty session
+	// ID and three cipher suites.
+	body := append([]byte{0x01, 0x00, 0x00, 0x2b, 0x03, 0x03}, make([]byte, 32)...)
+	body = append(body, 0x00, 0x00, 0x06, 0x00, 0x01, 0x00, 0x02, 0x00, 0x03)
+	record := append([]byte{0x16, 0x03, 0x01, 0x00, byte(len(body))}, body...)
+
+	hello, ok := reverseCipherSuites(record)
+	if !ok {
+		t.Fatal("expected the ClientHello to be rewritten")
+	}
+	got := hello[len(hello)-6:]
+	if !bytes.Equal(got, []byte{0x00, 0x03, 0x00, 0x02, 0x00, 0x01}) {
+		t.Errorf("cipher suites = % x, want reversed", got)
+	}
+	if record[len(record)-1] != 0x03 {
+		t.Error("expected the input to be left unmodified")
+	}
+}
+
+func TestCipherSuiteInfo(t *testing.T) {
+	info := cipherSuiteInfo(tls.TLS_RSA_WITH_RC4_128_SHA)
+	if info.Name != "TLS_RSA_WITH_RC4_128_SHA" || info.ID != "0x0005" || !info.Insecure {
+		t.Errorf("unexpected info: %+v", info)
+	}
+}
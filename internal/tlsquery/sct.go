@@ -0,0 +1,386 @@
+package tlsquery
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+	cryptobyteasn1 "golang.org/x/crypto/cryptobyte/asn1"
+	"golang.org/x/crypto/ocsp"
+)
+
+// SCT sources.
+const (
+	SCTSourceEmbedded     = "embedded"
+	SCTSourceTLSExtension = "tls_extension"
+	SCTSourceOCSP         = "ocsp"
+)
+
+// SCT verification statuses.
+const (
+	SCTValid      = "valid"
+	SCTInvalid    = "invalid"
+	SCTUnknownLog = "unknown_log"
+)
+
+var (
+	oidSCTList     = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 4, 2}
+	oidOCSPSCTList = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 4, 5}
+)
+
+// SCT is a Certificate Transparency signed certificate timestamp (RFC 6962).
+// Status and LogName are only set after verification against a log list.
+type SCT struct {
+	Source             string `json:"source"`
+	Version            int    `json:"version"`
+	LogID              string `json:"log_id"`
+	LogName            string `json:"log_name,omitempty"`
+	Timestamp          string `json:"timestamp"`
+	SignatureAlgorithm string `json:"signature_algorithm"`
+	Status             string `json:"status,omitempty"`
+	Error              string `json:"error,omitempty"`
+
+	sct *signedCertificateTimestamp
+}
+
+type signedCertificateTimestamp struct {
+	version    uint8
+	logID      [sha256.Size]byte
+	timestamp  uint64
+	extensions []byte
+	hashAlg    uint8
+	sigAlg     uint8
+	signature  []byte
+}
+
+// sctsFromCertificate extracts the SCTs embedded in cert.
+func sctsFromCertificate(cert *x509.Certificate) []SCT {
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidSCTList) {
+			return sctsFromExtension(ext.Value, SCTSourceEmbedded)
+		}
+	}
+	return nil
+}
+
+// sctsFromTLS converts the SCTs delivered in the TLS extension.
+func sctsFromTLS(raw [][]byte) []SCT {
+	var scts []SCT
+	for _, data := range raw {
+		scts = append(scts, newSCT(data, SCTSourceTLSExtension))
+	}
+	return scts
+}
+
+// sctsFromOCSP extracts the SCTs from the singleExtensions of a stapled
+// OCSP response for leaf.
+func sctsFromOCSP(raw []byte, leaf *x509.Certificate) []SCT {
+	if len(raw) == 0 {
+		return nil
+	}
+	resp, err := ocsp.ParseResponseForCert(raw, leaf, nil)
+	if err != nil {
+		return nil
+	}
+	for _, ext := range resp.Extensions {
+		if ext.Id.Equal(oidOCSPSCTList) {
+			return sctsFromExtension(ext.Value, SCTSourceOCSP)
+		}
+	}
+	return nil
+}
+
+// sctsFromExtension parses an extension value holding a DER OCTET STRING
+// with a TLS-encoded SignedCertificateTimestampList.
+func sctsFromExtension(value []byte, source string) []SCT {
+	var list []byte
+	if rest, err := asn1.Unmarshal(value, &list); err != nil || len(rest) > 0 {
+		return []SCT{{Source: source, Error: "invalid SCT list encoding"}}
+	}
+
+	s := cryptobyte.String(list)
+	var entries cryptobyte.String
+	if !s.ReadUint16LengthPrefixed(&entries) || !s.Empty() {
+		return []SCT{{Source: source, Error: "invalid SCT list encoding"}}
+	}
+
+	var scts []SCT
+	for !entries.Empty() {
+		var entry cryptobyte.String
+		if !entries.ReadUint16LengthPrefixed(&entry) {
+			return append(scts, SCT{Source: source, Error: "invalid SCT list encoding"})
+		}
+		scts = append(scts, newSCT(entry, source))
+	}
+	return scts
+}
+
+func newSCT(data []byte, source string) SCT {
+	sct, err := parseSCT(data)
+	if err != nil {
+		return SCT{Source: source, Error: err.Error()}
+	}
+	return SCT{
+		Source:             source,
+		Version:            int(sct.version) + 1,
+		LogID:              base64.StdEncoding.EncodeToString(sct.logID[:]),
+		Timestamp:          formatTime(time.UnixMilli(int64(sct.timestamp))),
+		SignatureAlgorithm: sctSignatureAlgorithm(sct.hashAlg, sct.sigAlg),
+		sct:                sct,
+	}
+}
+
+func parseSCT(data []byte) (*signedCertificateTimestamp, error) {
+	sct := &signedCertificateTimestamp{}
+	s := cryptobyte.String(data)
+	var logID, extensions, signature []byte
+	if !s.ReadUint8(&sct.version) {
+		return nil, errors.New("invalid SCT encoding")
+	}
+	if sct.version != 0 {
+		return nil, fmt.Errorf("unsupported SCT version %d", sct.version+1)
+	}
+	if !s.ReadBytes(&logID, sha256.Size) ||
+		!s.ReadUint64(&sct.timestamp) ||
+		!s.ReadUint16LengthPrefixed((*cryptobyte.String)(&extensions)) ||
+		!s.ReadUint8(&sct.hashAlg) ||
+		!s.ReadUint8(&sct.sigAlg) ||
+		!s.ReadUint16LengthPrefixed((*cryptobyte.String)(&signature)) ||
+		!s.Empty() {
+		return nil, errors.New("invalid SCT encoding")
+	}
+	copy(sct.logID[:], logID)
+	sct.extensions = extensions
+	sct.signature = signature
+	return sct, nil
+}
+
+// sctSignatureAlgorithm names a TLS SignatureAndHashAlgorithm like
+// x509.SignatureAlgorithm does.
+func sctSignatureAlgorithm(hashAlg, sigAlg uint8) string {
+	hashes := map[uint8]string{2: "SHA1", 3: "SHA224", 4: "SHA256", 5: "SHA384", 6: "SHA512"}
+	hash, ok := hashes[hashAlg]
+	if !ok {
+		hash = fmt.Sprintf("hash(%d)", hashAlg)
+	}
+	switch sigAlg {
+	case 1:
+		return hash + "-RSA"
+	case 3:
+		return "ECDSA-" + hash
+	default:
+		return fmt.Sprintf("%s-signature(%d)", hash, sigAlg)
+	}
+}
+
+// CTLog is a Certificate Transparency log from a log list.
+type CTLog struct {
+	Description string
+	Operator    string
+	Key         crypto.PublicKey
+}
+
+// CTLogList holds CT logs indexed by log ID.
+type CTLogList struct {
+	logs map[[sha256.Size]byte]*CTLog
+}
+
+// logListJSON is the subset of the log list v3 schema used by Chrome and
+// published at https://www.gstatic.com/ct/log_list/v3/log_list.json.
+type logListJSON struct {
+	Operators []struct {
+		Name      string        `json:"name"`
+		Logs      []logListItem `json:"logs"`
+		TiledLogs []logListItem `json:"tiled_logs"`
+	} `json:"operators"`
+}
+
+type logListItem struct {
+	Description string `json:"description"`
+	LogID       string `json:"log_id"`
+	Key         string `json:"key"`
+}
+
+// LoadCTLogList reads a log list JSON file.
+func LoadCTLogList(path string) (*CTLogList, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return ParseCTLogList(data)
+}
+
+// ParseCTLogList parses a log list in the v3 JSON schema. Log IDs are
+// computed from the keys rather than trusted from the file.
+func ParseCTLogList(data []byte) (*CTLogList, error) {
+	var parsed logListJSON
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("invalid log list: %w", err)
+	}
+
+	list := &CTLogList{logs: map[[sha256.Size]byte]*CTLog{}}
+	for _, operator := range parsed.Operators {
+		for _, item := range append(operator.Logs, operator.TiledLogs...) {
+			der, err := base64.StdEncoding.DecodeString(item.Key)
+			if err != nil {
+				return nil, fmt.Errorf("invalid key for log %q: %w", item.Description, err)
+			}
+			key, err := x509.ParsePKIXPublicKey(der)
+			if err != nil {
+				return nil, fmt.Errorf("invalid key for log %q: %w", item.Description, err)
+			}
+			list.logs[sha256.Sum256(der)] = &CTLog{Description: item.Description, Operator: operator.Name, Key: key}
+		}
+	}
+	if len(list.logs) == 0 {
+		return nil, errors.New("invalid log list: no logs found")
+	}
+	return list, nil
+}
+
+// VerifySCTs checks the signature of every SCT in chain against the logs in
+// list and sets its LogName and Status. Embedded SCTs are verified as
+// precertificate entries, which requires the issuer to be in the chain.
+func VerifySCTs(chain *ChainInfo, list *CTLogList) {
+	for i, cert := range chain.certs {
+		scts := chain.Certificates[i].SCTs
+		for j := range scts {
+			sct := &scts[j]
+			if sct.sct == nil {
+				continue
+			}
+			log, ok := list.logs[sct.sct.logID]
+			if !ok {
+				sct.Status = SCTUnknownLog
+				continue
+			}
+			sct.LogName = log.Description
+			if err := verifySCT(sct, cert, findIssuer(cert, chain.certs), log); err != nil {
+				sct.Status = SCTInvalid
+				sct.Error = err.Error()
+				continue
+			}
+			sct.Status = SCTValid
+		}
+	}
+}
+
+func verifySCT(sct *SCT, cert, issuer *x509.Certificate, log *CTLog) error {
+	b := cryptobyte.NewBuilder(nil)
+	b.AddUint8(sct.sct.version)
+	b.AddUint8(0) // signature_type: certificate_timestamp
+	b.AddUint64(sct.sct.timestamp)
+	if sct.Source == SCTSourceEmbedded {
+		if issuer == nil {
+			return errors.New("issuer not found in chain")
+		}
+		tbs, err := removeSCTExtension(cert.RawTBSCertificate)
+		if err != nil {
+			return err
+		}
+		issuerKeyHash := sha256.Sum256(issuer.RawSubjectPublicKeyInfo)
+		b.AddUint16(1) // entry_type: precert_entry
+		b.AddBytes(issuerKeyHash[:])
+		b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(tbs) })
+	} else {
+		b.AddUint16(0) // entry_type: x509_entry
+		b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(cert.Raw) })
+	}
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(sct.sct.extensions) })
+	signed, err := b.Bytes()
+	if err != nil {
+		return err
+	}
+
+	if sct.sct.hashAlg != 4 {
+		return fmt.Errorf("unsupported signature algorithm %s", sct.SignatureAlgorithm)
+	}
+	digest := sha256.Sum256(signed)
+	switch key := log.Key.(type) {
+	case *ecdsa.PublicKey:
+		if sct.sct.sigAlg != 3 || !ecdsa.VerifyASN1(key, digest[:], sct.sct.signature) {
+			return errors.New("signature verification failed")
+		}
+	case *rsa.PublicKey:
+		if sct.sct.sigAlg != 1 || rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sct.sct.signature) != nil {
+			return errors.New("signature verification failed")
+		}
+	default:
+		return fmt.Errorf("unsupported log key type %T", log.Key)
+	}
+	return nil
+}
+
+// removeSCTExtension reconstructs the precertificate TBSCertificate that
+// was logged by removing the SCT list extension from tbs.
+func removeSCTExtension(tbs []byte) ([]byte, error) {
+	errInvalid := errors.New("invalid TBSCertificate")
+	extensionsTag := cryptobyteasn1.Tag(3).ContextSpecific().Constructed()
+
+	input := cryptobyte.String(tbs)
+	var fields cryptobyte.String
+	if !input.ReadASN1(&fields, cryptobyteasn1.SEQUENCE) {
+		return nil, errInvalid
+	}
+
+	b := cryptobyte.NewBuilder(nil)
+	b.AddASN1(cryptobyteasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		for !fields.Empty() {
+			var field cryptobyte.String
+			var tag cryptobyteasn1.Tag
+			if !fields.ReadAnyASN1Element(&field, &tag) {
+				b.SetError(errInvalid)
+				return
+			}
+			if tag != extensionsTag {
+				b.AddBytes(field)
+				continue
+			}
+
+			var wrapper, extensions cryptobyte.String
+			if !field.ReadASN1(&wrapper, extensionsTag) || !wrapper.ReadASN1(&extensions, cryptobyteasn1.SEQUENCE) {
+				b.SetError(errInvalid)
+				return
+			}
+			var kept [][]byte
+			for !extensions.Empty() {
+				var ext cryptobyte.String
+				if !extensions.ReadASN1Element(&ext, cryptobyteasn1.SEQUENCE) {
+					b.SetError(errInvalid)
+					return
+				}
+				var body cryptobyte.String
+				var oid asn1.ObjectIdentifier
+				peek := ext
+				if !peek.ReadASN1(&body, cryptobyteasn1.SEQUENCE) || !body.ReadASN1ObjectIdentifier(&oid) {
+					b.SetError(errInvalid)
+					return
+				}
+				if !oid.Equal(oidSCTList) {
+					kept = append(kept, ext)
+				}
+			}
+			if len(kept) == 0 {
+				continue
+			}
+			b.AddASN1(extensionsTag, func(b *cryptobyte.Builder) {
+				b.AddASN1(cryptobyteasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+					for _, ext := range kept {
+						b.AddBytes(ext)
+					}
+				})
+			})
+		}
+	})
+	return b.Bytes()
+}
@@ -0,0 +1,270 @@
+package tlsquery
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/crypto/ocsp"
+)
+
+type testLog struct {
+	key *ecdsa.PrivateKey
+	id  [sha256.Size]byte
+}
+
+func newTestLog(t *testing.T) *testLog {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	return &testLog{key: key, id: sha256.Sum256(der)}
+}
+
+func (l *testLog) listJSON(t *testing.T, description string) []byte {
+	t.Helper()
+	der, _ := x509.MarshalPKIXPublicKey(&l.key.PublicKey)
+	return []byte(fmt.Sprintf(`{"operators": [{"name": "Test Operator", "logs": [{"description": %q, "log_id": %q, "key": %q}]}]}`,
+		description, base64.StdEncoding.EncodeToString(l.id[:]), base64.StdEncoding.EncodeToString(der)))
+}
+
+// sign returns a serialized SCT over entry, which is the entry_type and
+// signed_entry part of the digitally-signed struct.
+func (l *testLog) sign(t *testing.T, timestamp time.Time, entry func(b *cryptobyte.Builder)) []byte {
+	t.Helper()
+	ts := uint64(timestamp.UnixMilli())
+
+	signed := cryptobyte.NewBuilder(nil)
+	signed.AddUint8(0)
+	signed.AddUint8(0)
+	signed.AddUint64(ts)
+	entry(signed)
+	signed.AddUint16(0)
+	digest := sha256.Sum256(signed.BytesOrPanic())
+	sig, err := ecdsa.SignASN1(rand.Reader, l.key, digest[:])
+	if err != nil {
+		t.Fatalf("failed to sign SCT: %v", err)
+	}
+
+	b := cryptobyte.NewBuilder(nil)
+	b.AddUint8(0)
+	b.AddBytes(l.id[:])
+	b.AddUint64(ts)
+	b.AddUint16(0)
+	b.AddUint8(4)
+	b.AddUint8(3)
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(sig) })
+	return b.BytesOrPanic()
+}
+
+func x509Entry(cert *x509.Certificate) func(b *cryptobyte.Builder) {
+	return func(b *cryptobyte.Builder) {
+		b.AddUint16(0)
+		b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(cert.Raw) })
+	}
+}
+
+// sctListExtension encodes scts as an SCT list extension value.
+func sctListExtension(t *testing.T, scts ...[]byte) []byte {
+	t.Helper()
+	b := cryptobyte.NewBuilder(nil)
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		for _, sct := range scts {
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(sct) })
+		}
+	})
+	value, err := asn1.Marshal(b.BytesOrPanic())
+	if err != nil {
+		t.Fatalf("failed to marshal SCT list: %v", err)
+	}
+	return value
+}
+
+// newLoggedCert issues a certificate for key with an SCT for its
+// precertificate embedded, the way CAs do.
+func newLoggedCert(t *testing.T, log *testLog, issuer *testCert, key *ecdsa.PrivateKey, timestamp time.Time) (*x509.Certificate, *x509.Certificate) {
+	t.Helper()
+	tmpl := leafTemplate("localhost")
+	tmpl.SerialNumber = big.NewInt(42)
+
+	create := func() *x509.Certificate {
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, issuer.cert, &key.PublicKey, issuer.key)
+		if err != nil {
+			t.Fatalf("failed to create certificate: %v", err)
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			t.Fatalf("failed to parse certificate: %v", err)
+		}
+		return cert
+	}
+
+	precert := create()
+	issuerKeyHash := sha256.Sum256(issuer.cert.RawSubjectPublicKeyInfo)
+	sct := log.sign(t, timestamp, func(b *cryptobyte.Builder) {
+		b.AddUint16(1)
+		b.AddBytes(issuerKeyHash[:])
+		b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(precert.RawTBSCertificate) })
+	})
+	tmpl.ExtraExtensions = []pkix.Extension{{Id: oidSCTList, Value: sctListExtension(t, sct)}}
+	return create(), precert
+}
+
+func TestRemoveSCTExtension(t *testing.T) {
+	log := newTestLog(t)
+	root := newTestCert(t, caTemplate("Test Root"), nil)
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	cert, precert := newLoggedCert(t, log, root, key, time.Now())
+
+	tbs, err := removeSCTExtension(cert.RawTBSCertificate)
+	if err != nil {
+		t.Fatalf("removeSCTExtension failed: %v", err)
+	}
+	if !bytes.Equal(tbs, precert.RawTBSCertificate) {
+		t.Error("TBSCertificate without SCT extension does not match the precertificate")
+	}
+}
+
+func TestQueryContext_SCTs(t *testing.T) {
+	log := newTestLog(t)
+	root := newTestCert(t, caTemplate("Test Root"), nil)
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	timestamp := time.Now().Add(-time.Hour).UTC().Truncate(time.Millisecond)
+	leaf, _ := newLoggedCert(t, log, root, key, timestamp)
+
+	now := time.Now()
+	staple, err := ocsp.CreateResponse(root.cert, root.cert, ocsp.Response{
+		Status:          ocsp.Good,
+		SerialNumber:    leaf.SerialNumber,
+		ThisUpdate:      now,
+		NextUpdate:      now.Add(time.Hour),
+		ExtraExtensions: []pkix.Extension{{Id: oidOCSPSCTList, Value: sctListExtension(t, log.sign(t, timestamp, x509Entry(leaf)))}},
+	}, root.key)
+	if err != nil {
+		t.Fatalf("failed to create OCSP response: %v", err)
+	}
+
+	server, addr := startTLSServerWithConfig(t, &tls.Config{
+		Certificates: []tls.Certificate{{
+			Certificate:                 [][]byte{leaf.Raw, root.cert.Raw},
+			PrivateKey:                  key,
+			OCSPStaple:                  staple,
+			SignedCertificateTimestamps: [][]byte{log.sign(t, timestamp, x509Entry(leaf))},
+		}},
+	})
+	defer server.Close()
+
+	chain, err := QueryContext(context.Background(), addr, QueryOptions{})
+	if err != nil {
+		t.Fatalf("QueryContext failed: %v", err)
+	}
+
+	scts := chain.Certificates[0].SCTs
+	wantSources := []string{SCTSourceEmbedded, SCTSourceTLSExtension, SCTSourceOCSP}
+	if len(scts) != len(wantSources) {
+		t.Fatalf("expected %d SCTs, got %d: %+v", len(wantSources), len(scts), scts)
+	}
+	for i, sct := range scts {
+		if sct.Source != wantSources[i] {
+			t.Errorf("SCT %d: Source = %q, want %q", i, sct.Source, wantSources[i])
+		}
+		if sct.LogID != base64.StdEncoding.EncodeToString(log.id[:]) {
+			t.Errorf("SCT %d: unexpected log ID %s", i, sct.LogID)
+		}
+		if sct.Timestamp != formatTime(timestamp) || sct.SignatureAlgorithm != "ECDSA-SHA256" || sct.Version != 1 {
+			t.Errorf("SCT %d: unexpected %+v", i, sct)
+		}
+		if sct.Status != "" {
+			t.Errorf("SCT %d: status set before verification", i)
+		}
+	}
+
+	list, err := ParseCTLogList(log.listJSON(t, "Test Log"))
+	if err != nil {
+		t.Fatalf("ParseCTLogList failed: %v", err)
+	}
+	VerifySCTs(chain, list)
+	for i, sct := range chain.Certificates[0].SCTs {
+		if sct.Status != SCTValid || sct.LogName != "Test Log" {
+			t.Errorf("SCT %d (%s): Status = %q (%s), log %q", i, sct.Source, sct.Status, sct.Error, sct.LogName)
+		}
+	}
+}
+
+func TestVerifySCTs_Failures(t *testing.T) {
+	log := newTestLog(t)
+	root := newTestCert(t, caTemplate("Test Root"), nil)
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	leaf, _ := newLoggedCert(t, log, root, key, time.Now())
+	other := newTestCert(t, leafTemplate("other.example.com"), root)
+
+	list, err := ParseCTLogList(log.listJSON(t, "Test Log"))
+	if err != nil {
+		t.Fatalf("ParseCTLogList failed: %v", err)
+	}
+	unknownList, err := ParseCTLogList(newTestLog(t).listJSON(t, "Other Log"))
+	if err != nil {
+		t.Fatalf("ParseCTLogList failed: %v", err)
+	}
+
+	newChain := func(certs ...*x509.Certificate) *ChainInfo {
+		chain := &ChainInfo{certs: certs}
+		for _, cert := range certs {
+			chain.Certificates = append(chain.Certificates, CertInfoFromCert(cert))
+		}
+		return chain
+	}
+
+	// An SCT for a different certificate delivered over TLS.
+	chain := newChain(other.cert, root.cert)
+	chain.Certificates[0].SCTs = sctsFromTLS([][]byte{log.sign(t, time.Now(), x509Entry(leaf))})
+	VerifySCTs(chain, list)
+	if sct := chain.Certificates[0].SCTs[0]; sct.Status != SCTInvalid || sct.Error == "" {
+		t.Errorf("expected invalid SCT, got %+v", sct)
+	}
+
+	chain = newChain(leaf)
+	VerifySCTs(chain, list)
+	if sct := chain.Certificates[0].SCTs[0]; sct.Status != SCTInvalid {
+		t.Errorf("expected embedded SCT without issuer to be invalid, got %+v", sct)
+	}
+
+	chain = newChain(leaf, root.cert)
+	VerifySCTs(chain, unknownList)
+	if sct := chain.Certificates[0].SCTs[0]; sct.Status != SCTUnknownLog {
+		t.Errorf("expected unknown log, got %+v", sct)
+	}
+}
+
+func TestParseCTLogList_Invalid(t *testing.T) {
+	for _, data := range []string{`not json`, `{"operators": []}`, `{"operators": [{"logs": [{"key": "!!"}]}]}`} {
+		if _, err := ParseCTLogList([]byte(data)); err == nil {
+			t.Errorf("expected error for %s", data)
+		}
+	}
+}
+
+func TestSCTsFromExtension_Invalid(t *testing.T) {
+	scts := sctsFromExtension([]byte{0x04, 0x02, 0x00, 0x05}, SCTSourceEmbedded)
+	if len(scts) != 1 || scts[0].Error == "" {
+		t.Errorf("expected a single SCT with an error, got %+v", scts)
+	}
+}
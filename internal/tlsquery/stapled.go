@@ -0,0 +1,76 @@
+package tlsquery
+
+import (
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// StapledOCSP describes the OCSP response stapled to the handshake for the
+// leaf certificate. Issues lists stapling problems such as a missing, stale
+// or badly signed response.
+type StapledOCSP struct {
+	Stapled          bool     `json:"stapled"`
+	ResponderID      string   `json:"responder_id,omitempty"`
+	Status           string   `json:"status,omitempty"`
+	RevokedAt        string   `json:"revoked_at,omitempty"`
+	RevocationReason string   `json:"revocation_reason,omitempty"`
+	ProducedAt       string   `json:"produced_at,omitempty"`
+	ThisUpdate       string   `json:"this_update,omitempty"`
+	NextUpdate       string   `json:"next_update,omitempty"`
+	SignatureValid   bool     `json:"signature_valid"`
+	Stale            bool     `json:"stale"`
+	Issues           []string `json:"issues,omitempty"`
+}
+
+// StapledOCSPFromResponse decodes the stapled OCSP response raw for leaf.
+// The signature is checked against issuer, which may be nil if the chain
+// does not contain it.
+func StapledOCSPFromResponse(raw []byte, leaf, issuer *x509.Certificate, now time.Time) *StapledOCSP {
+	stapled := &StapledOCSP{Stapled: len(raw) > 0}
+	if !stapled.Stapled {
+		if len(leaf.OCSPServer) > 0 {
+			stapled.Issues = append(stapled.Issues, "no OCSP response stapled although the certificate names a responder")
+		}
+		return stapled
+	}
+
+	resp, err := ocsp.ParseResponseForCert(raw, leaf, nil)
+	if err != nil {
+		stapled.Issues = append(stapled.Issues, fmt.Sprintf("invalid stapled response: %v", err))
+		return stapled
+	}
+
+	if len(resp.RawResponderName) > 0 {
+		stapled.ResponderID = formatDistinguishedName(resp.RawResponderName)
+	} else {
+		stapled.ResponderID = "key hash " + formatKeyID(resp.ResponderKeyHash)
+	}
+	stapled.Status, stapled.RevokedAt, stapled.RevocationReason = ocspStatus(resp)
+	stapled.ProducedAt = formatTime(resp.ProducedAt)
+	stapled.ThisUpdate = formatTime(resp.ThisUpdate)
+	stapled.NextUpdate = formatTime(resp.NextUpdate)
+
+	switch {
+	case !resp.NextUpdate.IsZero() && now.After(resp.NextUpdate):
+		stapled.Stale = true
+		stapled.Issues = append(stapled.Issues, "stapled response is stale (nextUpdate has passed)")
+	case resp.ThisUpdate.After(now):
+		stapled.Issues = append(stapled.Issues, "stapled response is not yet valid (thisUpdate is in the future)")
+	}
+
+	if issuer == nil {
+		stapled.Issues = append(stapled.Issues, "issuer not found in chain, signature not verified")
+	} else if _, err := ocsp.ParseResponseForCert(raw, leaf, issuer); err != nil {
+		stapled.Issues = append(stapled.Issues, fmt.Sprintf("signature does not verify against the issuer: %v", err))
+	} else {
+		stapled.SignatureValid = true
+	}
+
+	if stapled.Status == "revoked" {
+		stapled.Issues = append(stapled.Issues, "stapled response reports the certificate as revoked")
+	}
+	return stapled
+}
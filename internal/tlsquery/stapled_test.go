@@ -0,0 +1,169 @@
+package tlsquery
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+func newOCSPResponse(t *testing.T, leaf, signer *testCert, template ocsp.Response) []byte {
+	t.Helper()
+	template.SerialNumber = leaf.cert.SerialNumber
+	resp, err := ocsp.CreateResponse(signer.cert, signer.cert, template, signer.key)
+	if err != nil {
+		t.Fatalf("failed to create OCSP response: %v", err)
+	}
+	return resp
+}
+
+func TestStapledOCSPFromResponse(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	root := newTestCert(t, caTemplate("Test Root"), nil)
+	other := newTestCert(t, caTemplate("Other Root"), nil)
+
+	tmpl := leafTemplate("test.example.com")
+	tmpl.OCSPServer = []string{"http://ocsp.example.com"}
+	leaf := newTestCert(t, tmpl, root)
+	noResponder := newTestCert(t, leafTemplate("test.example.com"), root)
+
+	fresh := ocsp.Response{Status: ocsp.Good, ThisUpdate: now.Add(-time.Hour), NextUpdate: now.Add(time.Hour)}
+	stale := ocsp.Response{Status: ocsp.Good, ThisUpdate: now.Add(-2 * time.Hour), NextUpdate: now.Add(-time.Hour)}
+	revoked := ocsp.Response{Status: ocsp.Revoked, ThisUpdate: now, RevokedAt: now.Add(-time.Hour), RevocationReason: ocsp.Superseded}
+
+	tests := []struct {
+		name          string
+		raw           []byte
+		leaf          *testCert
+		issuer        *testCert
+		wantStatus    string
+		wantSignature bool
+		wantStale     bool
+		wantIssue     string
+	}{
+		{
+			name:          "valid",
+			raw:           newOCSPResponse(t, leaf, root, fresh),
+			leaf:          leaf,
+			issuer:        root,
+			wantStatus:    "good",
+			wantSignature: true,
+		},
+		{
+			name:          "stale",
+			raw:           newOCSPResponse(t, leaf, root, stale),
+			leaf:          leaf,
+			issuer:        root,
+			wantStatus:    "good",
+			wantSignature: true,
+			wantStale:     true,
+			wantIssue:     "stale",
+		},
+		{
+			name:          "revoked",
+			raw:           newOCSPResponse(t, leaf, root, revoked),
+			leaf:          leaf,
+			issuer:        root,
+			wantStatus:    "revoked",
+			wantSignature: true,
+			wantIssue:     "revoked",
+		},
+		{
+			name:       "signed by another CA",
+			raw:        newOCSPResponse(t, leaf, other, fresh),
+			leaf:       leaf,
+			issuer:     root,
+			wantStatus: "good",
+			wantIssue:  "signature does not verify",
+		},
+		{
+			name:       "issuer missing",
+			raw:        newOCSPResponse(t, leaf, root, fresh),
+			leaf:       leaf,
+			wantStatus: "good",
+			wantIssue:  "issuer not found",
+		},
+		{
+			name:      "missing",
+			leaf:      leaf,
+			issuer:    root,
+			wantIssue: "no OCSP response stapled",
+		},
+		{
+			name:   "not expected",
+			leaf:   noResponder,
+			issuer: root,
+		},
+		{
+			name:      "garbage",
+			raw:       []byte{0x30, 0x00},
+			leaf:      leaf,
+			issuer:    root,
+			wantIssue: "invalid stapled response",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := StapledOCSPFromResponse(tt.raw, tt.leaf.cert, certOf(tt.issuer), now)
+
+			if got.Stapled != (len(tt.raw) > 0) {
+				t.Errorf("Stapled = %t", got.Stapled)
+			}
+			if got.Status != tt.wantStatus {
+				t.Errorf("Status = %q, want %q", got.Status, tt.wantStatus)
+			}
+			if got.SignatureValid != tt.wantSignature {
+				t.Errorf("SignatureValid = %t, want %t", got.SignatureValid, tt.wantSignature)
+			}
+			if got.Stale != tt.wantStale {
+				t.Errorf("Stale = %t, want %t", got.Stale, tt.wantStale)
+			}
+			issues := strings.Join(got.Issues, "; ")
+			if tt.wantIssue == "" && issues != "" {
+				t.Errorf("unexpected issues: %s", issues)
+			}
+			if !strings.Contains(issues, tt.wantIssue) {
+				t.Errorf("issues %q do not mention %q", issues, tt.wantIssue)
+			}
+			if got.Status != "" && (got.ResponderID != "CN=Test Root" && got.ResponderID != "CN=Other Root") {
+				t.Errorf("unexpected responder ID %q", got.ResponderID)
+			}
+		})
+	}
+}
+
+// certOf returns the certificate of c, or nil if c is nil.
+func certOf(c *testCert) *x509.Certificate {
+	if c == nil {
+		return nil
+	}
+	return c.cert
+}
+
+func TestQueryContext_StapledOCSP(t *testing.T) {
+	root := newTestCert(t, caTemplate("Test Root"), nil)
+	leaf := newTestCert(t, leafTemplate("localhost"), root)
+
+	now := time.Now()
+	cert := tls.Certificate{
+		Certificate: [][]byte{leaf.cert.Raw, root.cert.Raw},
+		PrivateKey:  leaf.key,
+		OCSPStaple:  newOCSPResponse(t, leaf, root, ocsp.Response{Status: ocsp.Good, ThisUpdate: now, NextUpdate: now.Add(time.Hour)}),
+	}
+	server, addr := startTLSServerWithConfig(t, &tls.Config{Certificates: []tls.Certificate{cert}})
+	defer server.Close()
+
+	chain, err := QueryContext(context.Background(), addr, QueryOptions{})
+	if err != nil {
+		t.Fatalf("QueryContext failed: %v", err)
+	}
+	stapled := chain.StapledOCSP
+	if stapled == nil || !stapled.Stapled || stapled.Status != "good" || !stapled.SignatureValid {
+		t.Errorf("unexpected stapled OCSP %+v", stapled)
+	}
+}
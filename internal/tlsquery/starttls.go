@@ -0,0 +1,409 @@
+package tlsquery
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+)
+
+// startTLSProtocol describes a plaintext protocol that can be upgraded to TLS.
+type startTLSProtocol struct {
+	defaultPort string
+	upgrade     func(conn net.Conn, host string) error
+}
+
+var startTLSProtocols = map[string]startTLSProtocol{
+	"smtp": {"25", upgradeSMTP},
+	"imap": {"143", upgradeIMAP},
+	"pop3": {"110", upgradePOP3},
+	"ftp":  {"21", upgradeFTP},
+	"ldap": {"389", upgradeLDAP},
+	"xmpp": {"5222", upgradeXMPP},
+
+	"postgres": {"5432", upgradePostgres},
+	"mysql":    {"3306", upgradeMySQL},
+}
+
+// StartTLSProtocols returns the names of the supported STARTTLS protocols.
+func StartTLSProtocols() []string {
+	names := make([]string, 0, len(startTLSProtocols))
+	for name := range startTLSProtocols {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DefaultPort returns the well-known port for a STARTTLS protocol, or 443
+// for direct TLS and unknown protocols.
+func DefaultPort(protocol string) string {
+	if p, ok := startTLSProtocols[protocol]; ok {
+		return p.defaultPort
+	}
+	return "443"
+}
+
+func lookupStartTLS(protocol string) (startTLSProtocol, error) {
+	p, ok := startTLSProtocols[protocol]
+	if !ok {
+		return startTLSProtocol{}, fmt.Errorf("unsupported STARTTLS protocol %q (valid: %s)", protocol, strings.Join(StartTLSProtocols(), ", "))
+	}
+	return p, nil
+}
+
+// startTLS runs the plaintext negotiation for protocol on conn. On success
+// the next bytes on conn belong to the TLS handshake.
+func startTLS(conn net.Conn, protocol, host string) error {
+	p, err := lookupStartTLS(protocol)
+	if err != nil {
+		return err
+	}
+	if err := p.upgrade(conn, host); err != nil {
+		return fmt.Errorf("%s STARTTLS failed: %w", protocol, err)
+	}
+	return nil
+}
+
+// readReply reads a (possibly multi-line) numeric reply as used by SMTP and
+// FTP, where continuation lines use "NNN-" and the final line "NNN ".
+func readReply(r *bufio.Reader) (string, string, error) {
+	var lines []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", "", err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		lines = append(lines, line)
+		if len(line) < 4 {
+			return "", "", fmt.Errorf("malformed reply: %q", line)
+		}
+		if line[3] == ' ' {
+			return line[:3], strings.Join(lines, "\n"), nil
+		}
+	}
+}
+
+func expectReply(r *bufio.Reader, code string) error {
+	got, text, err := readReply(r)
+	if err != nil {
+		return err
+	}
+	if got != code {
+		return fmt.Errorf("unexpected reply: %s", text)
+	}
+	return nil
+}
+
+func upgradeSMTP(conn net.Conn, host string) error {
+	r := bufio.NewReader(conn)
+	if err := expectReply(r, "220"); err != nil {
+		return fmt.Errorf("greeting: %w", err)
+	}
+	if _, err := io.WriteString(conn, "EHLO tlsctl\r\n"); err != nil {
+		return err
+	}
+	if err := expectReply(r, "250"); err != nil {
+		return fmt.Errorf("EHLO: %w", err)
+	}
+	if _, err := io.WriteString(conn, "STARTTLS\r\n"); err != nil {
+		return err
+	}
+	if err := expectReply(r, "220"); err != nil {
+		return fmt.Errorf("server refused STARTTLS: %w", err)
+	}
+	return nil
+}
+
+func upgradeFTP(conn net.Conn, host string) error {
+	r := bufio.NewReader(conn)
+	if err := expectReply(r, "220"); err != nil {
+		return fmt.Errorf("greeting: %w", err)
+	}
+	if _, err := io.WriteString(conn, "AUTH TLS\r\n"); err != nil {
+		return err
+	}
+	if err := expectReply(r, "234"); err != nil {
+		return fmt.Errorf("server refused AUTH TLS: %w", err)
+	}
+	return nil
+}
+
+func upgradePOP3(conn net.Conn, host string) error {
+	r := bufio.NewReader(conn)
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "+OK") {
+		return fmt.Errorf("greeting: unexpected reply: %s", strings.TrimSpace(line))
+	}
+	if _, err := io.WriteString(conn, "STLS\r\n"); err != nil {
+		return err
+	}
+	line, err = r.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "+OK") {
+		return fmt.Errorf("server refused STLS: %s", strings.TrimSpace(line))
+	}
+	return nil
+}
+
+func upgradeIMAP(conn net.Conn, host string) error {
+	r := bufio.NewReader(conn)
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "* OK") {
+		return fmt.Errorf("greeting: unexpected reply: %s", strings.TrimSpace(line))
+	}
+	if _, err := io.WriteString(conn, "a001 STARTTLS\r\n"); err != nil {
+		return err
+	}
+	for {
+		line, err = r.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		if !strings.HasPrefix(line, "a001 ") {
+			continue
+		}
+		if !strings.HasPrefix(line, "a001 OK") {
+			return fmt.Errorf("server refused STARTTLS: %s", strings.TrimSpace(line))
+		}
+		return nil
+	}
+}
+
+// ldapStartTLSOID is the LDAP extended operation that requests a TLS upgrade (RFC 4511).
+const ldapStartTLSOID = "1.3.6.1.4.1.1466.20037"
+
+func upgradeLDAP(conn net.Conn, host string) error {
+	// LDAPMessage { messageID 1, ExtendedRequest [APPLICATION 23] { requestName [0] OID } }
+	request := []byte{0x30, byte(7 + len(ldapStartTLSOID)), 0x02, 0x01, 0x01,
+		0x77, byte(2 + len(ldapStartTLSOID)), 0x80, byte(len(ldapStartTLSOID))}
+	request = append(request, ldapStartTLSOID...)
+	if _, err := conn.Write(request); err != nil {
+		return err
+	}
+
+	response, err := readBER(conn)
+	if err != nil {
+		return err
+	}
+
+	var msg asn1.RawValue
+	if _, err := asn1.Unmarshal(response, &msg); err != nil {
+		return fmt.Errorf("malformed response: %w", err)
+	}
+
+	var messageID int
+	rest, err := asn1.Unmarshal(msg.Bytes, &messageID)
+	if err != nil {
+		return fmt.Errorf("malformed response: %w", err)
+	}
+	var op asn1.RawValue
+	if _, err := asn1.Unmarshal(rest, &op); err != nil {
+		return fmt.Errorf("malformed response: %w", err)
+	}
+	if op.Class != asn1.ClassApplication || op.Tag != 24 {
+		return fmt.Errorf("unexpected response operation %d", op.Tag)
+	}
+	var resultCode asn1.Enumerated
+	if _, err := asn1.Unmarshal(op.Bytes, &resultCode); err != nil {
+		return fmt.Errorf("malformed response: %w", err)
+	}
+	if resultCode != 0 {
+		return fmt.Errorf("server refused StartTLS: result code %d", resultCode)
+	}
+	return nil
+}
+
+// maxBERLength bounds the LDAP responses read by readBER. An
+// ExtendedResponse is far smaller; the limit keeps a hostile server from
+// forcing a large allocation.
+const maxBERLength = 64 << 10
+
+// readBER reads a single definite-length BER element from r.
+func readBER(r io.Reader) ([]byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	length := int(header[1])
+	if length&0x80 != 0 {
+		n := length & 0x7f
+		if n == 0 || n > 4 {
+			return nil, fmt.Errorf("unsupported BER length encoding")
+		}
+		lenBytes := make([]byte, n)
+		if _, err := io.ReadFull(r, lenBytes); err != nil {
+			return nil, err
+		}
+		header = append(header, lenBytes...)
+		length = 0
+		for _, b := range lenBytes {
+			length = length<<8 | int(b)
+		}
+	}
+	if length > maxBERLength {
+		return nil, fmt.Errorf("BER element too long: %d bytes", length)
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return append(header, body...), nil
+}
+
+func upgradeXMPP(conn net.Conn, host string) error {
+	// The host comes from the command line and is quoted as an attribute.
+	var to bytes.Buffer
+	xml.EscapeText(&to, []byte(host))
+	header := fmt.Sprintf("<?xml version='1.0'?><stream:stream xmlns='jabber:client' "+
+		"xmlns:stream='http://etherx.jabber.org/streams' to='%s' version='1.0'>", to.String())
+	if _, err := io.WriteString(conn, header); err != nil {
+		return err
+	}
+
+	r := bufio.NewReader(conn)
+	features, err := readUntil(r, "</stream:features>")
+	if err != nil {
+		return fmt.Errorf("reading stream features: %w", err)
+	}
+	if !bytes.Contains(features, []byte("urn:ietf:params:xml:ns:xmpp-tls")) {
+		return fmt.Errorf("server does not offer STARTTLS")
+	}
+
+	if _, err := io.WriteString(conn, "<starttls xmlns='urn:ietf:params:xml:ns:xmpp-tls'/>"); err != nil {
+		return err
+	}
+	reply, err := readUntil(r, ">")
+	if err != nil {
+		return err
+	}
+	if !bytes.Contains(reply, []byte("<proceed")) {
+		return fmt.Errorf("server refused STARTTLS: %s", strings.TrimSpace(string(reply)))
+	}
+	return nil
+}
+
+// readUntil reads from r until the accumulated data ends with marker.
+func readUntil(r *bufio.Reader, marker string) ([]byte, error) {
+	var buf []byte
+	for !bytes.HasSuffix(buf, []byte(marker)) {
+		b, err := r.ReadByte()
+		if err != nil {
+			return buf, err
+		}
+		buf = append(buf, b)
+	}
+	return buf, nil
+}
+
+// postgresSSLRequestCode is the protocol version number that identifies an
+// SSLRequest message in the PostgreSQL startup phase.
+const postgresSSLRequestCode = 80877103
+
+func upgradePostgres(conn net.Conn, host string) error {
+	request := make([]byte, 8)
+	binary.BigEndian.PutUint32(request[0:4], 8)
+	binary.BigEndian.PutUint32(request[4:8], postgresSSLRequestCode)
+	if _, err := conn.Write(request); err != nil {
+		return err
+	}
+
+	reply := make([]byte, 1)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	switch reply[0] {
+	case 'S':
+		return nil
+	case 'N':
+		return fmt.Errorf("server refused SSLRequest: TLS is not enabled")
+	case 'E':
+		return fmt.Errorf("server rejected SSLRequest with an error response")
+	default:
+		return fmt.Errorf("unexpected SSLRequest reply %q", reply[0])
+	}
+}
+
+// MySQL capability flags used during the TLS upgrade.
+const (
+	mysqlClientProtocol41       = 0x00000200
+	mysqlClientSSL              = 0x00000800
+	mysqlClientSecureConnection = 0x00008000
+)
+
+func upgradeMySQL(conn net.Conn, host string) error {
+	payload, err := readMySQLPacket(conn)
+	if err != nil {
+		return fmt.Errorf("reading handshake: %w", err)
+	}
+	if len(payload) > 0 && payload[0] == 0xff {
+		return fmt.Errorf("server error: %s", mysqlErrorMessage(payload))
+	}
+	if len(payload) == 0 || payload[0] != 10 {
+		return fmt.Errorf("unsupported handshake protocol version")
+	}
+
+	// protocol version, NUL-terminated server version, connection id (4),
+	// auth data part 1 (8), filler (1), then the lower capability flags (2).
+	end := bytes.IndexByte(payload[1:], 0)
+	if end < 0 || len(payload) < 1+end+1+4+8+1+2 {
+		return fmt.Errorf("malformed handshake packet")
+	}
+	offset := 1 + end + 1 + 4 + 8 + 1
+	capabilities := binary.LittleEndian.Uint16(payload[offset : offset+2])
+	if capabilities&mysqlClientSSL == 0 {
+		return fmt.Errorf("server does not support TLS")
+	}
+
+	// SSLRequest: capability flags (4), max packet size (4), charset (1), reserved (23).
+	request := make([]byte, 4+32)
+	request[0] = 32
+	request[3] = 1
+	binary.LittleEndian.PutUint32(request[4:8], mysqlClientProtocol41|mysqlClientSSL|mysqlClientSecureConnection)
+	binary.LittleEndian.PutUint32(request[8:12], 1<<24)
+	request[12] = 33 // utf8_general_ci
+	_, err = conn.Write(request)
+	return err
+}
+
+func readMySQLPacket(r io.Reader) ([]byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	length := int(header[0]) | int(header[1])<<8 | int(header[2])<<16
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// mysqlErrorMessage extracts the message from an ERR packet: 0xff, error
+// code (2), optional SQL state marker and state (6), message.
+func mysqlErrorMessage(payload []byte) string {
+	if len(payload) < 3 {
+		return "unknown error"
+	}
+	code := binary.LittleEndian.Uint16(payload[1:3])
+	msg := payload[3:]
+	if len(msg) >= 6 && msg[0] == '#' {
+		msg = msg[6:]
+	}
+	return fmt.Sprintf("%d %s", code, msg)
+}
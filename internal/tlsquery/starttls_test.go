@@ -0,0 +1,311 @@
+package tlsquery
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+// startPlaintextServer accepts connections, runs negotiate on each one and,
+// if it returns true, completes a TLS handshake with a test certificate.
+func startPlaintextServer(t *testing.T, negotiate func(conn net.Conn, r *bufio.Reader) bool) string {
+	t.Helper()
+
+	leaf := newTestCert(t, leafTemplate("mail.example.com"), nil)
+	config := &tls.Config{
+		Certificates: []tls.Certificate{{
+			Certificate: [][]byte{leaf.cert.Raw},
+			PrivateKey:  leaf.key,
+		}},
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				if negotiate(conn, r) {
+					_ = tls.Server(&bufferedConn{conn, r}, config).Handshake()
+				}
+			}()
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func readLine(r *bufio.Reader) string {
+	line, _ := r.ReadString('\n')
+	return strings.TrimRight(line, "\r\n")
+}
+
+func fakeSMTP(conn net.Conn, r *bufio.Reader) bool {
+	io.WriteString(conn, "220-mail.example.com ESMTP\r\n220 ready\r\n")
+	if !strings.HasPrefix(readLine(r), "EHLO ") {
+		return false
+	}
+	io.WriteString(conn, "250-mail.example.com\r\n250-PIPELINING\r\n250 STARTTLS\r\n")
+	if readLine(r) != "STARTTLS" {
+		return false
+	}
+	io.WriteString(conn, "220 go ahead\r\n")
+	return true
+}
+
+func fakeIMAP(conn net.Conn, r *bufio.Reader) bool {
+	io.WriteString(conn, "* OK [CAPABILITY IMAP4rev1 STARTTLS] ready\r\n")
+	tag, cmd, _ := strings.Cut(readLine(r), " ")
+	if cmd != "STARTTLS" {
+		return false
+	}
+	io.WriteString(conn, "* informational line\r\n"+tag+" OK Begin TLS negotiation now\r\n")
+	return true
+}
+
+func fakePOP3(conn net.Conn, r *bufio.Reader) bool {
+	io.WriteString(conn, "+OK POP3 ready\r\n")
+	if readLine(r) != "STLS" {
+		return false
+	}
+	io.WriteString(conn, "+OK Begin TLS negotiation\r\n")
+	return true
+}
+
+func fakeFTP(conn net.Conn, r *bufio.Reader) bool {
+	io.WriteString(conn, "220 FTP ready\r\n")
+	if readLine(r) != "AUTH TLS" {
+		return false
+	}
+	io.WriteString(conn, "234 AUTH TLS successful\r\n")
+	return true
+}
+
+func fakeLDAP(resultCode byte) func(net.Conn, *bufio.Reader) bool {
+	return func(conn net.Conn, r *bufio.Reader) bool {
+		request, err := readBER(r)
+		if err != nil || !strings.Contains(string(request), ldapStartTLSOID) {
+			return false
+		}
+		// ExtendedResponse { resultCode, matchedDN "", diagnosticMessage "" }
+		conn.Write([]byte{0x30, 0x0c, 0x02, 0x01, 0x01, 0x78, 0x07, 0x0a, 0x01, resultCode, 0x04, 0x00, 0x04, 0x00})
+		return resultCode == 0
+	}
+}
+
+func fakeXMPP(conn net.Conn, r *bufio.Reader) bool {
+	if _, err := readUntil(r, "version='1.0'>"); err != nil {
+		return false
+	}
+	io.WriteString(conn, "<?xml version='1.0'?><stream:stream xmlns='jabber:client' "+
+		"xmlns:stream='http://etherx.jabber.org/streams' id='1' version='1.0'>"+
+		"<stream:features><starttls xmlns='urn:ietf:params:xml:ns:xmpp-tls'><required/></starttls></stream:features>")
+	if _, err := readUntil(r, "/>"); err != nil {
+		return false
+	}
+	io.WriteString(conn, "<proceed xmlns='urn:ietf:params:xml:ns:xmpp-tls'/>")
+	return true
+}
+
+func fakePostgres(reply byte) func(net.Conn, *bufio.Reader) bool {
+	return func(conn net.Conn, r *bufio.Reader) bool {
+		request := make([]byte, 8)
+		if _, err := io.ReadFull(r, request); err != nil {
+			return false
+		}
+		if binary.BigEndian.Uint32(request[4:]) != postgresSSLRequestCode {
+			return false
+		}
+		conn.Write([]byte{reply})
+		return reply == 'S'
+	}
+}
+
+func mysqlPacket(seq byte, payload []byte) []byte {
+	n := len(payload)
+	return append([]byte{byte(n), byte(n >> 8), byte(n >> 16), seq}, payload...)
+}
+
+func fakeMySQL(capabilities uint16) func(net.Conn, *bufio.Reader) bool {
+	return func(conn net.Conn, r *bufio.Reader) bool {
+		handshake := []byte{10}
+		handshake = append(handshake, "8.0.36\x00"...)
+		handshake = append(handshake, 1, 0, 0, 0)
+		handshake = append(handshake, "abcdefgh"...)
+		handshake = append(handshake, 0, byte(capabilities), byte(capabilities>>8), 33, 2, 0, 0, 0)
+		conn.Write(mysqlPacket(0, handshake))
+		if capabilities&mysqlClientSSL == 0 {
+			return false
+		}
+
+		request, err := readMySQLPacket(r)
+		if err != nil || len(request) != 32 {
+			return false
+		}
+		return binary.LittleEndian.Uint32(request)&mysqlClientSSL != 0
+	}
+}
+
+func TestQueryContext_StartTLS(t *testing.T) {
+	tests := []struct {
+		protocol  string
+		negotiate func(net.Conn, *bufio.Reader) bool
+	}{
+		{"smtp", fakeSMTP},
+		{"imap", fakeIMAP},
+		{"pop3", fakePOP3},
+		{"ftp", fakeFTP},
+		{"ldap", fakeLDAP(0)},
+		{"xmpp", fakeXMPP},
+		{"postgres", fakePostgres('S')},
+		{"mysql", fakeMySQL(0xffff)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.protocol, func(t *testing.T) {
+			addr := startPlaintextServer(t, tt.negotiate)
+
+			chain, err := QueryContext(context.Background(), addr, QueryOptions{StartTLS: tt.protocol})
+			if err != nil {
+				t.Fatalf("QueryContext failed: %v", err)
+			}
+			if got := chain.Certificates[0].CommonName; got != "mail.example.com" {
+				t.Errorf("expected CN 'mail.example.com', got %q", got)
+			}
+		})
+	}
+}
+
+func TestQueryContext_StartTLSRefused(t *testing.T) {
+	tests := []struct {
+		protocol  string
+		negotiate func(net.Conn, *bufio.Reader) bool
+	}{
+		{"smtp", func(conn net.Conn, r *bufio.Reader) bool {
+			io.WriteString(conn, "220 ready\r\n")
+			readLine(r)
+			io.WriteString(conn, "250 mail.example.com\r\n")
+			readLine(r)
+			io.WriteString(conn, "454 TLS not available\r\n")
+			return false
+		}},
+		{"imap", func(conn net.Conn, r *bufio.Reader) bool {
+			io.WriteString(conn, "* OK ready\r\n")
+			tag, _, _ := strings.Cut(readLine(r), " ")
+			io.WriteString(conn, tag+" BAD STARTTLS not supported\r\n")
+			return false
+		}},
+		{"pop3", func(conn net.Conn, r *bufio.Reader) bool {
+			io.WriteString(conn, "+OK ready\r\n")
+			readLine(r)
+			io.WriteString(conn, "-ERR command not supported\r\n")
+			return false
+		}},
+		{"ftp", func(conn net.Conn, r *bufio.Reader) bool {
+			io.WriteString(conn, "220 ready\r\n")
+			readLine(r)
+			io.WriteString(conn, "530 Please login with USER and PASS\r\n")
+			return false
+		}},
+		{"ldap", fakeLDAP(2)},
+		{"xmpp", func(conn net.Conn, r *bufio.Reader) bool {
+			readUntil(r, "version='1.0'>")
+			io.WriteString(conn, "<stream:stream version='1.0'><stream:features></stream:features>")
+			return false
+		}},
+		{"postgres", fakePostgres('N')},
+		{"mysql", fakeMySQL(0xffff &^ mysqlClientSSL)},
+		{"mysql", func(conn net.Conn, r *bufio.Reader) bool {
+			conn.Write(mysqlPacket(0, append([]byte{0xff, 0x6a, 0x04, '#', 'H', 'Y', '0', '0', '0'}, "Host is blocked"...)))
+			return false
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.protocol, func(t *testing.T) {
+			addr := startPlaintextServer(t, tt.negotiate)
+
+			_, err := QueryContext(context.Background(), addr, QueryOptions{StartTLS: tt.protocol})
+			if err == nil {
+				t.Fatal("expected error when server refuses the upgrade")
+			}
+			if !strings.Contains(err.Error(), tt.protocol+" STARTTLS failed") {
+				t.Errorf("error %q does not name the failed protocol", err)
+			}
+		})
+	}
+}
+
+func TestQueryContext_UnsupportedStartTLS(t *testing.T) {
+	_, err := QueryContext(context.Background(), "127.0.0.1:1", QueryOptions{StartTLS: "gopher"})
+	if err == nil || !strings.Contains(err.Error(), "unsupported STARTTLS protocol") {
+		t.Errorf("expected unsupported protocol error, got %v", err)
+	}
+}
+
+func TestDefaultPort(t *testing.T) {
+	tests := map[string]string{
+		"":         "443",
+		"smtp":     "25",
+		"imap":     "143",
+		"ldap":     "389",
+		"xmpp":     "5222",
+		"postgres": "5432",
+		"mysql":    "3306",
+	}
+	for protocol, want := range tests {
+		if got := DefaultPort(protocol); got != want {
+			t.Errorf("DefaultPort(%q) = %q, want %q", protocol, got, want)
+		}
+	}
+}
+
+func TestReadBER(t *testing.T) {
+	element := []byte{0x30, 0x81, 0x03, 0x02, 0x01, 0x05}
+	got, err := readBER(bytes.NewReader(element))
+	if err != nil {
+		t.Fatalf("readBER failed: %v", err)
+	}
+	if !bytes.Equal(got, element) {
+		t.Errorf("readBER = % x, want % x", got, element)
+	}
+
+	// A 4-byte length of almost 4 GiB must be rejected before allocating.
+	_, err = readBER(bytes.NewReader([]byte{0x30, 0x84, 0xff, 0xff, 0xff, 0xf0}))
+	if err == nil || !strings.Contains(err.Error(), "too long") {
+		t.Errorf("expected length error, got %v", err)
+	}
+}
+
+func TestUpgradeXMPP_EscapesHost(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		upgradeXMPP(client, "x'/><evil/><a b='&")
+		client.Close()
+	}()
+
+	header, err := readUntil(bufio.NewReader(server), "version='1.0'>")
+	server.Close()
+	if err != nil {
+		t.Fatalf("failed to read stream header: %v", err)
+	}
+	if want := "to='x&#39;/&gt;&lt;evil/&gt;&lt;a b=&#39;&amp;'"; !strings.Contains(string(header), want) {
+		t.Errorf("stream header %q does not contain %q", header, want)
+	}
+}
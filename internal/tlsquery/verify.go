@@ -0,0 +1,130 @@
+package tlsquery
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Verification holds the result of validating a certificate chain.
+type Verification struct {
+	Verified bool   `json:"verified"`
+	Hostname string `json:"hostname,omitempty"`
+	// HostnameMatch reports whether the leaf is valid for Hostname. It is
+	// nil when no hostname was checked.
+	HostnameMatch *bool      `json:"hostname_match,omitempty"`
+	Chains        [][]string `json:"chains,omitempty"`
+	Reason        string     `json:"reason,omitempty"`
+	Error         string     `json:"error,omitempty"`
+	// IncompleteChain reports that the server did not send all the
+	// intermediates needed to build the chain, and that they were fetched
+	// from AIA caIssuers URLs.
+	IncompleteChain bool `json:"incomplete_chain,omitempty"`
+}
+
+// Verification failure reasons.
+const (
+	ReasonExpired           = "expired"
+	ReasonUnknownAuthority  = "unknown_authority"
+	ReasonNameMismatch      = "name_mismatch"
+	ReasonIncompatibleUsage = "incompatible_usage"
+	ReasonNotAuthorized     = "not_authorized_to_sign"
+	ReasonNameConstraints   = "name_constraints"
+	ReasonOther             = "other"
+)
+
+// VerifyChain validates certs (leaf first) against roots and checks that the
+// leaf is valid for hostname. A nil roots pool uses the system roots. An
+// empty hostname skips the hostname check.
+func VerifyChain(certs []*x509.Certificate, hostname string, roots *x509.CertPool) *Verification {
+	v := &Verification{Hostname: hostname}
+	if len(certs) == 0 {
+		v.Reason = ReasonOther
+		v.Error = "no certificates to verify"
+		return v
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	var errs []string
+	chains, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+	})
+	if err != nil {
+		v.Reason = failureReason(err)
+		errs = append(errs, err.Error())
+	}
+	for _, chain := range chains {
+		subjects := make([]string, len(chain))
+		for i, cert := range chain {
+			subjects[i] = cert.Subject.String()
+		}
+		v.Chains = append(v.Chains, subjects)
+	}
+
+	if hostname != "" {
+		err := certs[0].VerifyHostname(hostname)
+		if err != nil {
+			if v.Reason == "" {
+				v.Reason = ReasonNameMismatch
+			}
+			errs = append(errs, err.Error())
+		}
+		match := err == nil
+		v.HostnameMatch = &match
+	}
+
+	v.Verified = len(errs) == 0
+	v.Error = strings.Join(errs, "; ")
+	return v
+}
+
+func failureReason(err error) string {
+	var invalidErr x509.CertificateInvalidError
+	if errors.As(err, &invalidErr) {
+		switch invalidErr.Reason {
+		case x509.Expired:
+			return ReasonExpired
+		case x509.IncompatibleUsage:
+			return ReasonIncompatibleUsage
+		case x509.NotAuthorizedToSign:
+			return ReasonNotAuthorized
+		case x509.CANotAuthorizedForThisName, x509.CANotAuthorizedForExtKeyUsage,
+			x509.UnconstrainedName, x509.TooManyConstraints:
+			return ReasonNameConstraints
+		}
+		return ReasonOther
+	}
+
+	var authorityErr x509.UnknownAuthorityError
+	if errors.As(err, &authorityErr) {
+		return ReasonUnknownAuthority
+	}
+
+	var hostnameErr x509.HostnameError
+	if errors.As(err, &hostnameErr) {
+		return ReasonNameMismatch
+	}
+
+	return ReasonOther
+}
+
+// LoadCertPool reads a PEM bundle and returns a pool with all its certificates.
+func LoadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+	return pool, nil
+}
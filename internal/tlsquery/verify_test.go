@@ -0,0 +1,191 @@
+package tlsquery
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// newTestCert creates a certificate from template, signed by parent, or
+// self-signed when parent is nil.
+func newTestCert(t *testing.T, template *x509.Certificate, parent *testCert) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	signerCert, signerKey := template, key
+	if parent != nil {
+		signerCert, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signerCert, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+
+	return &testCert{cert: cert, key: key}
+}
+
+func caTemplate(cn string) *x509.Certificate {
+	return &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+}
+
+func leafTemplate(cn string) *x509.Certificate {
+	return &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{cn},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+}
+
+func TestVerifyChain(t *testing.T) {
+	root := newTestCert(t, caTemplate("Test Root"), nil)
+	intermediate := newTestCert(t, caTemplate("Test Intermediate"), root)
+	leaf := newTestCert(t, leafTemplate("test.example.com"), intermediate)
+
+	expiredTmpl := leafTemplate("test.example.com")
+	expiredTmpl.NotBefore = time.Now().Add(-48 * time.Hour)
+	expiredTmpl.NotAfter = time.Now().Add(-24 * time.Hour)
+	expired := newTestCert(t, expiredTmpl, intermediate)
+
+	clientTmpl := leafTemplate("test.example.com")
+	clientTmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	clientOnly := newTestCert(t, clientTmpl, intermediate)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root.cert)
+
+	tests := []struct {
+		name          string
+		certs         []*x509.Certificate
+		hostname      string
+		roots         *x509.CertPool
+		wantVerified  bool
+		wantReason    string
+		wantHostMatch bool
+	}{
+		{
+			name:          "valid chain",
+			certs:         []*x509.Certificate{leaf.cert, intermediate.cert},
+			hostname:      "test.example.com",
+			roots:         roots,
+			wantVerified:  true,
+			wantHostMatch: true,
+		},
+		{
+			name:          "valid chain with IP hostname",
+			certs:         []*x509.Certificate{leaf.cert, intermediate.cert},
+			hostname:      "127.0.0.1",
+			roots:         roots,
+			wantVerified:  true,
+			wantHostMatch: true,
+		},
+		{
+			name:          "missing intermediate",
+			certs:         []*x509.Certificate{leaf.cert},
+			hostname:      "test.example.com",
+			roots:         roots,
+			wantReason:    ReasonUnknownAuthority,
+			wantHostMatch: true,
+		},
+		{
+			name:          "untrusted root",
+			certs:         []*x509.Certificate{leaf.cert, intermediate.cert},
+			hostname:      "test.example.com",
+			roots:         x509.NewCertPool(),
+			wantReason:    ReasonUnknownAuthority,
+			wantHostMatch: true,
+		},
+		{
+			name:       "name mismatch",
+			certs:      []*x509.Certificate{leaf.cert, intermediate.cert},
+			hostname:   "other.example.com",
+			roots:      roots,
+			wantReason: ReasonNameMismatch,
+		},
+		{
+			name:          "expired leaf",
+			certs:         []*x509.Certificate{expired.cert, intermediate.cert},
+			hostname:      "test.example.com",
+			roots:         roots,
+			wantReason:    ReasonExpired,
+			wantHostMatch: true,
+		},
+		{
+			name:          "incompatible usage",
+			certs:         []*x509.Certificate{clientOnly.cert, intermediate.cert},
+			hostname:      "test.example.com",
+			roots:         roots,
+			wantReason:    ReasonIncompatibleUsage,
+			wantHostMatch: true,
+		},
+		{
+			name:         "no hostname",
+			certs:        []*x509.Certificate{leaf.cert, intermediate.cert},
+			roots:        roots,
+			wantVerified: true,
+		},
+		{
+			name:       "no certificates",
+			wantReason: ReasonOther,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := VerifyChain(tt.certs, tt.hostname, tt.roots)
+			if v.Verified != tt.wantVerified {
+				t.Errorf("Verified = %t, want %t (error: %s)", v.Verified, tt.wantVerified, v.Error)
+			}
+			if v.Reason != tt.wantReason {
+				t.Errorf("Reason = %q, want %q", v.Reason, tt.wantReason)
+			}
+			if tt.hostname == "" {
+				if v.HostnameMatch != nil {
+					t.Errorf("HostnameMatch = %t, want unset", *v.HostnameMatch)
+				}
+			} else if v.HostnameMatch == nil || *v.HostnameMatch != tt.wantHostMatch {
+				t.Errorf("HostnameMatch = %v, want %t", v.HostnameMatch, tt.wantHostMatch)
+			}
+			if tt.wantVerified && len(v.Chains) == 0 {
+				t.Error("expected at least one verified chain")
+			}
+			if !tt.wantVerified && v.Error == "" {
+				t.Error("expected error message for failed verification")
+			}
+		})
+	}
+}